@@ -16,6 +16,7 @@
 package brokerapi
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/handlers"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
 )
 
 type BrokerCredentials struct {
@@ -40,8 +42,18 @@ func NewWithOptions(serviceBroker domain.ServiceBroker, logger *slog.Logger, opt
 	var cfg config
 	WithOptions(opts...)(&cfg)
 
-	mw := append(append(cfg.authMiddleware, defaultMiddleware(logger)...), cfg.additionalMiddleware...)
-	r := router(serviceBroker, logger)
+	if cfg.lockout != nil {
+		for _, wrapper := range cfg.basicAuth {
+			wrapper.WithLockout(cfg.lockout)
+		}
+	}
+
+	if cfg.validateCatalog {
+		validateCatalog(serviceBroker, logger)
+	}
+
+	mw := append(append(cfg.authMiddleware, defaultMiddleware(logger, cfg)...), cfg.additionalMiddleware...)
+	r := router(serviceBroker, logger, cfg)
 
 	return middleware.Use(r, mw...)
 }
@@ -51,15 +63,77 @@ func NewWithCustomAuth(serviceBroker domain.ServiceBroker, logger *slog.Logger,
 }
 
 type config struct {
-	authMiddleware       []func(http.Handler) http.Handler
-	additionalMiddleware []func(http.Handler) http.Handler
+	authMiddleware            []func(http.Handler) http.Handler
+	additionalMiddleware      []func(http.Handler) http.Handler
+	strictOriginatingIdentity bool
+	handlerOptions            []handlers.Option
+	validateCatalog           bool
+	idPolicy                  *middlewares.IDPolicy
+	basicAuth                 []*auth.Wrapper
+	lockout                   *auth.Lockout
 }
 
 type Option func(*config)
 
 func WithBrokerCredentials(brokerCredentials BrokerCredentials) Option {
 	return func(c *config) {
-		c.authMiddleware = append(c.authMiddleware, auth.NewWrapper(brokerCredentials.Username, brokerCredentials.Password).Wrap)
+		wrapper := auth.NewWrapper(brokerCredentials.Username, brokerCredentials.Password)
+		c.basicAuth = append(c.basicAuth, wrapper)
+		c.authMiddleware = append(c.authMiddleware, wrapper.Wrap)
+	}
+}
+
+// WithBrokerCredentialProvider authenticates requests using basic authentication, accepting whichever
+// credentials the provider returns at the time of the request. This allows credentials to be rotated
+// without restarting the broker.
+func WithBrokerCredentialProvider(provider auth.CredentialProvider) Option {
+	return func(c *config) {
+		wrapper := auth.NewWrapperWithProvider(provider)
+		c.basicAuth = append(c.basicAuth, wrapper)
+		c.authMiddleware = append(c.authMiddleware, wrapper.Wrap)
+	}
+}
+
+// WithLockout protects the basic authentication of WithBrokerCredentials() and WithBrokerCredentialProvider()
+// against brute-force attacks, as described in auth.Lockout. Source IPs and usernames with too many failed
+// attempts are rejected with 429 Too Many Requests. For other authenticators, use auth.Wrapper.WithLockout().
+func WithLockout(lockoutConfig auth.LockoutConfig) Option {
+	return func(c *config) {
+		c.lockout = auth.NewLockout(lockoutConfig)
+	}
+}
+
+// WithBearerAuth authenticates requests using signed JWT bearer tokens, verified against
+// the key set in the BearerConfig. It is an alternative to `WithBrokerCredentials()`.
+func WithBearerAuth(bearerConfig auth.BearerConfig) Option {
+	return func(c *config) {
+		c.authMiddleware = append(c.authMiddleware, auth.NewBearerWrapper(bearerConfig).Wrap)
+	}
+}
+
+// WithClientCertAuth authenticates requests using the client certificate presented in the TLS handshake.
+// The certificate must chain to one of the configured certificate authorities and match the allowlists
+// in the ClientCertConfig.
+func WithClientCertAuth(clientCertConfig auth.ClientCertConfig) Option {
+	return func(c *config) {
+		c.authMiddleware = append(c.authMiddleware, auth.NewClientCertWrapper(clientCertConfig).Wrap)
+	}
+}
+
+// WithHMACAuth authenticates requests signed with a shared secret, as described in auth.HMACWrapper.
+// Clients can sign requests using auth.HMACTransport.
+func WithHMACAuth(hmacConfig auth.HMACConfig) Option {
+	return func(c *config) {
+		c.authMiddleware = append(c.authMiddleware, auth.NewHMACWrapper(hmacConfig).Wrap)
+	}
+}
+
+// WithAnyAuth authenticates requests using whichever of the authenticators succeeds first, for example
+// to accept both basic authentication and bearer tokens. Unauthenticated requests are answered
+// with a WWW-Authenticate challenge for each scheme. See auth.Any().
+func WithAnyAuth(authenticators ...auth.Authenticator) Option {
+	return func(c *config) {
+		c.authMiddleware = append(c.authMiddleware, auth.Any(authenticators...).Wrap)
 	}
 }
 
@@ -81,6 +155,112 @@ func WithAdditionalMiddleware(m func(http.Handler) http.Handler) Option {
 	}
 }
 
+// WithStrictOriginatingIdentity rejects requests with a malformed X-Broker-API-Originating-Identity
+// header with 400 Bad Request. By default such headers are ignored, and only the unparsed header is
+// available in the request context.
+func WithStrictOriginatingIdentity() Option {
+	return func(c *config) {
+		c.strictOriginatingIdentity = true
+	}
+}
+
+// WithParameterValidation validates the parameters of provision, update and bind requests against the
+// schemas of the plan in the catalog. Requests with parameters that do not conform are rejected with
+// 400 Bad Request and a list of violations, without calling the ServiceBroker. Schemas may use
+// draft-04 to 2020-12 keywords, but only local "$ref" references are supported. Parameters larger
+// than 256 KiB are rejected.
+func WithParameterValidation() Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithParameterValidation())
+	}
+}
+
+// WithoutMaintenanceInfoCheck disables the check that the maintenance_info of provision and update requests
+// matches the plan in the catalog. By default, mismatched requests are rejected with 422 Unprocessable Entity
+// and the MaintenanceInfoConflict error, as required by the Open Service Broker API.
+func WithoutMaintenanceInfoCheck() Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithoutMaintenanceInfoCheck())
+	}
+}
+
+// WithStrictDecoding rejects provision, update and bind requests whose bodies are larger than maxBodyBytes
+// (1MiB if maxBodyBytes is not positive) with 413 Request Entity Too Large, and those with unknown top-level
+// fields, data after the JSON object, or "parameters" or "context" that are not JSON objects with
+// 422 Unprocessable Entity. The error description gives the line and column of the problem.
+func WithStrictDecoding(maxBodyBytes int64) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithStrictDecoding(maxBodyBytes))
+	}
+}
+
+// WithAllowedPlatforms rejects provision, update and bind requests with 400 Bad Request if the "platform"
+// of their context is not one of those listed, for example domain.PlatformCloudFoundry, or if their context
+// is malformed. Requests without a context are accepted, as the context is optional before API version 2.12.
+func WithAllowedPlatforms(platforms ...string) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithAllowedPlatforms(platforms...))
+	}
+}
+
+// WithIDPolicy rejects requests to every endpoint with 400 Bad Request if the instance_id or binding_id in
+// the path does not conform to the policy, for example because it is too long or is not a UUID. The
+// ServiceBroker is not called.
+func WithIDPolicy(policy middlewares.IDPolicy) Option {
+	return func(c *config) {
+		c.idPolicy = &policy
+	}
+}
+
+// WithCatalogResolution sets what happens when the service_id or plan_id of a deprovision, unbind,
+// last operation or get instance request, or the plan_id of an update that keeps the plan, is not in
+// the catalog: the request can be passed to the
+// ServiceBroker (the default), passed on with a warning in the log, or rejected with 400 Bad Request.
+// Passing requests on allows brokers to clean up instances of plans that have been removed from the catalog.
+func WithCatalogResolution(resolution handlers.CatalogResolution) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithCatalogResolution(resolution))
+	}
+}
+
+// WithOperationEngine runs the domain.OperationJob returned by the ServiceBroker from Provision, Update,
+// Deprovision, Bind and Unbind on the engine, responding with 202 Accepted and the ID of the operation as
+// the operation data. Last operation requests for these operations are answered by the engine, without
+// calling the ServiceBroker. The engine should be shut down when the broker stops.
+func WithOperationEngine(engine *operations.Engine) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithOperationEngine(engine))
+	}
+}
+
+// WithRetryAfterPolicy suggests a polling interval with the Retry-After header of 202 Accepted responses,
+// and of last operation responses for operations whose age is known, when the ServiceBroker does not set
+// RetryAfter itself. handlers.BackoffRetryAfter() polls less often as an operation ages.
+func WithRetryAfterPolicy(policy handlers.RetryAfterPolicy) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithRetryAfterPolicy(policy))
+	}
+}
+
+// WithOperationTimeout calls onTimeout when an asynchronous operation is reported as failed because it
+// did not finish within the maximum_polling_duration of its plan, so that the ServiceBroker can clean up.
+// It is called synchronously by the last operation request, which waits for it to return, with the
+// context of the request.
+func WithOperationTimeout(onTimeout handlers.OperationTimeoutFunc) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, handlers.WithOperationTimeout(onTimeout))
+	}
+}
+
+// WithCatalogValidation checks the catalog with domain.ValidateCatalog() when the handler is created,
+// and logs an error for each problem found, so that mistakes are noticed before the platform
+// rejects the broker registration
+func WithCatalogValidation() Option {
+	return func(c *config) {
+		c.validateCatalog = true
+	}
+}
+
 func WithOptions(opts ...Option) Option {
 	return func(c *config) {
 		for _, o := range opts {
@@ -89,30 +269,57 @@ func WithOptions(opts ...Option) Option {
 	}
 }
 
-func router(serviceBroker ServiceBroker, logger *slog.Logger) http.Handler {
-	apiHandler := handlers.NewApiHandler(serviceBroker, logger)
+func router(serviceBroker ServiceBroker, logger *slog.Logger, cfg config) http.Handler {
+	apiHandler := handlers.NewApiHandler(serviceBroker, logger, cfg.handlerOptions...)
 	r := http.NewServeMux()
-	r.HandleFunc("GET /v2/catalog", apiHandler.Catalog)
 
-	r.HandleFunc("PUT /v2/service_instances/{instance_id}", apiHandler.Provision)
-	r.HandleFunc("GET /v2/service_instances/{instance_id}", apiHandler.GetInstance)
-	r.HandleFunc("PATCH /v2/service_instances/{instance_id}", apiHandler.Update)
-	r.HandleFunc("DELETE /v2/service_instances/{instance_id}", apiHandler.Deprovision)
+	handle := r.HandleFunc
+	if cfg.idPolicy != nil {
+		validateIDs := middlewares.IDPolicyMiddleware{Logger: logger, Policy: *cfg.idPolicy}.ValidateIDs
+		handle = func(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+			r.Handle(pattern, validateIDs(http.HandlerFunc(handler)))
+		}
+	}
+
+	handle("GET /v2/catalog", apiHandler.Catalog)
+
+	handle("PUT /v2/service_instances/{instance_id}", apiHandler.Provision)
+	handle("GET /v2/service_instances/{instance_id}", apiHandler.GetInstance)
+	handle("PATCH /v2/service_instances/{instance_id}", apiHandler.Update)
+	handle("DELETE /v2/service_instances/{instance_id}", apiHandler.Deprovision)
 
-	r.HandleFunc("GET /v2/service_instances/{instance_id}/last_operation", apiHandler.LastOperation)
+	handle("GET /v2/service_instances/{instance_id}/last_operation", apiHandler.LastOperation)
 
-	r.HandleFunc("PUT /v2/service_instances/{instance_id}/service_bindings/{binding_id}", apiHandler.Bind)
-	r.HandleFunc("GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}", apiHandler.GetBinding)
-	r.HandleFunc("DELETE /v2/service_instances/{instance_id}/service_bindings/{binding_id}", apiHandler.Unbind)
+	handle("PUT /v2/service_instances/{instance_id}/service_bindings/{binding_id}", apiHandler.Bind)
+	handle("GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}", apiHandler.GetBinding)
+	handle("DELETE /v2/service_instances/{instance_id}/service_bindings/{binding_id}", apiHandler.Unbind)
 
-	r.HandleFunc("GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}/last_operation", apiHandler.LastBindingOperation)
+	handle("GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}/last_operation", apiHandler.LastBindingOperation)
 
 	return r
 }
 
-func defaultMiddleware(logger *slog.Logger) []func(http.Handler) http.Handler {
+func validateCatalog(serviceBroker ServiceBroker, logger *slog.Logger) {
+	services, err := serviceBroker.Services(context.Background())
+	if err != nil {
+		logger.Error("catalog-validation.services-error", slog.Any("error", err))
+		return
+	}
+
+	for _, problem := range domain.ValidateCatalog(services) {
+		logger.Error("catalog-validation.invalid-catalog", slog.String("path", problem.Path), slog.String("error", problem.Message))
+	}
+}
+
+func defaultMiddleware(logger *slog.Logger, cfg config) []func(http.Handler) http.Handler {
+	var validateOriginatingIdentity func(http.Handler) http.Handler
+	if cfg.strictOriginatingIdentity {
+		validateOriginatingIdentity = middlewares.OriginatingIdentityMiddleware{Logger: logger}.ValidateOriginatingIdentityHdr
+	}
+
 	return []func(http.Handler) http.Handler{
 		middlewares.APIVersionMiddleware{Logger: logger}.ValidateAPIVersionHdr,
+		validateOriginatingIdentity,
 		middlewares.AddCorrelationIDToContext,
 		middlewares.AddOriginatingIdentityToContext,
 		middlewares.AddInfoLocationToContext,
@@ -18,6 +18,9 @@ package brokerapi_test
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -27,11 +30,13 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/pivotal-cf/brokerapi/v12"
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/fakes"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
 )
@@ -268,6 +273,22 @@ var _ = Describe("Service Broker API", func() {
 					"broker should have been hit when authentication succeeded",
 				)
 			})
+
+			When("a lockout is configured", func() {
+				BeforeEach(func() {
+					brokerAPI = brokerapi.New(fakeServiceBroker, brokerLogger, credentials, brokerapi.WithLockout(auth.LockoutConfig{MaxAttempts: 1, Logger: brokerLogger}))
+				})
+
+				It("returns 429 after too many failed attempts without calling the service broker", func() {
+					Expect(makeRequestWithBasicAuth(credentials.Username, "fake_password")).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+					response := makeRequestWithBasicAuth(credentials.Username, credentials.Password)
+					Expect(response).To(HaveHTTPStatus(http.StatusTooManyRequests))
+					Expect(response.Header.Get("Retry-After")).To(Equal("1"))
+					Expect(fakeServiceBroker.BrokerCalled).To(BeFalse())
+					Expect(lastLogLine()).To(HaveKeyWithValue("msg", "auth.locked-out"))
+				})
+			})
 		})
 
 		When("using custom authentication", func() {
@@ -346,6 +367,157 @@ var _ = Describe("Service Broker API", func() {
 				)
 			})
 		})
+
+		When("using bearer authentication", func() {
+			secret := []byte("bearer-secret")
+
+			makeRequestWithToken := func(claims map[string]any) (response *http.Response) {
+				withServer(brokerAPI, func(r requester) {
+					request := must(http.NewRequest("GET", "/v2/catalog", nil))
+					request.Header.Set("Authorization", "Bearer "+signHS256Token(secret, claims))
+					request.Header.Add("X-Broker-API-Version", apiVersion)
+
+					response = r.Do(request)
+				})
+				return response
+			}
+
+			BeforeEach(func() {
+				keys := auth.NewKeySet()
+				Expect(keys.Add("", secret)).To(Succeed())
+				brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBearerAuth(auth.BearerConfig{
+					Keys:     keys,
+					Audience: "broker",
+				}))
+			})
+
+			It("returns 401 when there is no authorization header", func() {
+				response := makeRequestWithoutAuth()
+				Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+				Expect(fakeServiceBroker.BrokerCalled).To(BeFalse())
+			})
+
+			It("returns 401 when the token is for another audience", func() {
+				response := makeRequestWithToken(map[string]any{"aud": "other", "exp": time.Now().Add(time.Hour).Unix()})
+				Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+				Expect(fakeServiceBroker.BrokerCalled).To(BeFalse())
+			})
+
+			It("calls through to the service broker when authenticated", func() {
+				response := makeRequestWithToken(map[string]any{"aud": "broker", "exp": time.Now().Add(time.Hour).Unix()})
+				Expect(response).To(HaveHTTPStatus(http.StatusOK))
+				Expect(fakeServiceBroker.BrokerCalled).To(BeTrue())
+			})
+		})
+
+		When("using scoped credentials", func() {
+			makeScopedRequest := func(method, path, body string) (response *http.Response) {
+				withServer(brokerAPI, func(r requester) {
+					request := must(http.NewRequest(method, path, strings.NewReader(body)))
+					request.SetBasicAuth("read-only", "password")
+					request.Header.Add("X-Broker-API-Version", apiVersion)
+
+					response = r.Do(request)
+				})
+				return response
+			}
+
+			BeforeEach(func() {
+				brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBrokerCredentialProvider(auth.StaticCredentials{
+					auth.NewCredential("read-only", "password").WithScope(auth.Scope{
+						Operations: auth.ReadOnly,
+						ServiceIDs: []string{fakeServiceBroker.ServiceID},
+					}),
+				}))
+			})
+
+			It("allows permitted operations", func() {
+				response := makeScopedRequest("GET", "/v2/catalog", "")
+				Expect(response).To(HaveHTTPStatus(http.StatusOK))
+				Expect(fakeServiceBroker.BrokerCalled).To(BeTrue())
+			})
+
+			It("returns 403 for operations that are not permitted without calling the broker", func() {
+				response := makeScopedRequest("PUT", "/v2/service_instances/instance-id",
+					fmt.Sprintf(`{"service_id":%q,"plan_id":%q}`, fakeServiceBroker.ServiceID, fakeServiceBroker.PlanID))
+				Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+				Expect(readBody(response)).To(MatchJSON(`{"description":"the credentials used are not permitted to perform this operation"}`))
+				Expect(fakeServiceBroker.ProvisionedInstances).To(BeEmpty())
+			})
+
+			It("returns 403 for services that are not permitted", func() {
+				response := makeScopedRequest("GET", "/v2/service_instances/instance-id/last_operation?service_id=other-service", "")
+				Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+			})
+
+			It("allows polling without a service_id", func() {
+				response := makeScopedRequest("GET", "/v2/service_instances/instance-id/last_operation", "")
+				Expect(response).To(HaveHTTPStatus(http.StatusOK))
+			})
+		})
+
+		When("accepting any of several schemes", func() {
+			secret := []byte("bearer-secret")
+
+			BeforeEach(func() {
+				keys := auth.NewKeySet()
+				Expect(keys.Add("", secret)).To(Succeed())
+				brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithAnyAuth(
+					auth.NewWrapper(credentials.Username, credentials.Password),
+					auth.NewBearerWrapper(auth.BearerConfig{Keys: keys}),
+				))
+			})
+
+			It("returns 401 with a challenge for each scheme when there is no authorization header", func() {
+				response := makeRequestWithoutAuth()
+				Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+				Expect(response.Header.Values("WWW-Authenticate")).To(Equal([]string{`Basic realm="service-broker"`, "Bearer"}))
+			})
+
+			It("calls through to the service broker with either scheme", func() {
+				withServer(brokerAPI, func(r requester) {
+					request := must(http.NewRequest("GET", "/v2/catalog", nil))
+					request.SetBasicAuth(credentials.Username, credentials.Password)
+					request.Header.Add("X-Broker-API-Version", apiVersion)
+					Expect(r.Do(request)).To(HaveHTTPStatus(http.StatusOK))
+
+					request = must(http.NewRequest("GET", "/v2/catalog", nil))
+					request.Header.Set("Authorization", "Bearer "+signHS256Token(secret, map[string]any{"exp": time.Now().Add(time.Hour).Unix()}))
+					request.Header.Add("X-Broker-API-Version", apiVersion)
+					Expect(r.Do(request)).To(HaveHTTPStatus(http.StatusOK))
+				})
+			})
+		})
+
+		When("using HMAC request signing", func() {
+			secret := []byte("hmac-secret")
+
+			BeforeEach(func() {
+				brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithHMACAuth(auth.HMACConfig{
+					Keys:            map[string][]byte{"automation": secret},
+					RequiredHeaders: []string{"X-Broker-API-Version"},
+				}))
+			})
+
+			It("returns 401 when the request is not signed", func() {
+				response := makeRequestWithoutAuth()
+				Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+				Expect(fakeServiceBroker.BrokerCalled).To(BeFalse())
+			})
+
+			It("calls through to the service broker when the request is signed", func() {
+				server := httptest.NewServer(brokerAPI)
+				defer server.Close()
+
+				request := must(http.NewRequest("GET", server.URL+"/v2/catalog", nil))
+				request.Header.Add("X-Broker-API-Version", apiVersion)
+				client := &http.Client{Transport: &auth.HMACTransport{KeyID: "automation", Secret: secret}}
+
+				response := must(client.Do(request))
+				Expect(response).To(HaveHTTPStatus(http.StatusOK))
+				Expect(fakeServiceBroker.BrokerCalled).To(BeTrue())
+			})
+		})
 	})
 
 	Describe("OriginatingIdentityHeader", func() {
@@ -394,6 +566,127 @@ var _ = Describe("Service Broker API", func() {
 				Expect(fakeServiceBroker.ServicesCallCount()).To(Equal(1), "Services was not called")
 				ctx := fakeServiceBroker.ServicesArgsForCall(0)
 				Expect(ctx.Value(middlewares.OriginatingIdentityKey)).To(Equal(""))
+				Expect(brokerapi.RetrieveOriginatingIdentityFromContext(ctx)).To(BeNil())
+			})
+		})
+
+		When("X-Broker-API-Originating-Identity is well formed", func() {
+			It("adds the parsed identity to the context", func() {
+				req.Header.Add("X-Broker-API-Originating-Identity", "cloudfoundry "+base64.StdEncoding.EncodeToString([]byte(`{"user_id":"some-user"}`)))
+
+				_, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+
+				ctx := fakeServiceBroker.ServicesArgsForCall(0)
+				identity := brokerapi.RetrieveOriginatingIdentityFromContext(ctx)
+				Expect(identity).NotTo(BeNil())
+				Expect(identity.Platform).To(Equal("cloudfoundry"))
+				Expect(identity.User()).To(Equal("some-user"))
+			})
+		})
+
+		When("strict originating identity checking is enabled", func() {
+			BeforeEach(func() {
+				testServer.Config.Handler = brokerapi.New(fakeServiceBroker, brokerLogger, credentials, brokerapi.WithStrictOriginatingIdentity())
+			})
+
+			It("rejects a malformed header", func() {
+				req.Header.Add("X-Broker-API-Originating-Identity", "Originating Identity Name")
+
+				response, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+				Expect(readBody(response)).To(MatchJSON(`{"Description":"originating identity value is not valid base64: illegal base64 data at input byte 8"}`))
+				Expect(fakeServiceBroker.ServicesCallCount()).To(BeZero())
+			})
+
+			It("accepts requests without the header", func() {
+				response, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(response).To(HaveHTTPStatus(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("IDPolicy", func() {
+		var (
+			fakeServiceBroker *fakes.AutoFakeServiceBroker
+			testServer        *httptest.Server
+		)
+
+		request := func(method, path string) *http.Response {
+			req, err := http.NewRequest(method, testServer.URL+path, nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Add("X-Broker-API-Version", "2.14")
+			req.SetBasicAuth(credentials.Username, credentials.Password)
+
+			response, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			return response
+		}
+
+		BeforeEach(func() {
+			fakeServiceBroker = new(fakes.AutoFakeServiceBroker)
+			policy := middlewares.IDPolicy{
+				MaxLength:         40,
+				AllowedCharacters: func(r rune) bool { return r == '-' || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') },
+			}
+			testServer = httptest.NewServer(brokerapi.New(fakeServiceBroker, brokerLogger, credentials, brokerapi.WithIDPolicy(policy)))
+		})
+
+		AfterEach(func() {
+			testServer.Close()
+		})
+
+		It("accepts conforming IDs", func() {
+			Expect(request(http.MethodGet, "/v2/service_instances/an-instance")).To(HaveHTTPStatus(http.StatusOK))
+			Expect(request(http.MethodGet, "/v2/service_instances/an-instance/service_bindings/a-binding")).To(HaveHTTPStatus(http.StatusOK))
+			Expect(fakeServiceBroker.GetInstanceCallCount()).To(Equal(1))
+			Expect(fakeServiceBroker.GetBindingCallCount()).To(Equal(1))
+		})
+
+		It("rejects an instance ID that is too long", func() {
+			response := request(http.MethodDelete, "/v2/service_instances/"+strings.Repeat("a", 41)+"?service_id=a-service&plan_id=a-plan")
+			Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+			Expect(readBody(response)).To(MatchJSON(`{"description":"instance_id must not be longer than 40 characters"}`))
+			Expect(fakeServiceBroker.DeprovisionCallCount()).To(BeZero())
+			Expect(lastLogLine()).To(HaveKeyWithValue("msg", "id-policy-check.id-invalid"))
+		})
+
+		It("rejects a binding ID with a character that is not allowed", func() {
+			response := request(http.MethodPut, "/v2/service_instances/an-instance/service_bindings/A_BINDING")
+			Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+			Expect(readBody(response)).To(MatchJSON(`{"description":"binding_id must not contain the character 'A'"}`))
+			Expect(fakeServiceBroker.BindCallCount()).To(BeZero())
+		})
+
+		It("applies to the last operation endpoints", func() {
+			response := request(http.MethodGet, "/v2/service_instances/an-instance/service_bindings/a.binding/last_operation")
+			Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+			Expect(fakeServiceBroker.LastBindingOperationCallCount()).To(BeZero())
+		})
+
+		When("only UUIDs are allowed", func() {
+			BeforeEach(func() {
+				testServer.Config.Handler = brokerapi.New(fakeServiceBroker, brokerLogger, credentials, brokerapi.WithIDPolicy(middlewares.IDPolicy{UUIDOnly: true}))
+			})
+
+			It("accepts a UUID", func() {
+				response := request(http.MethodGet, "/v2/service_instances/1f7cd5ab-5ba1-4e8e-a2ad-5e5e5d4f20c3/last_operation")
+				Expect(response).To(HaveHTTPStatus(http.StatusOK))
+			})
+
+			It("rejects other IDs", func() {
+				for _, id := range []string{"an-instance", "1f7cd5ab5ba14e8ea2ad5e5e5d4f20c3", "urn:uuid:1f7cd5ab-5ba1-4e8e-a2ad-5e5e5d4f20c3"} {
+					response := request(http.MethodGet, "/v2/service_instances/"+id+"/last_operation")
+					Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+					Expect(readBody(response)).To(MatchJSON(`{"description":"instance_id must be a UUID"}`))
+				}
+				Expect(fakeServiceBroker.LastOperationCallCount()).To(BeZero())
+			})
+
+			It("does not apply to the catalog", func() {
+				Expect(request(http.MethodGet, "/v2/catalog")).To(HaveHTTPStatus(http.StatusOK))
 			})
 		})
 	})
@@ -608,6 +901,23 @@ var _ = Describe("Service Broker API", func() {
 			Expect(response.Body.String()).To(MatchJSON(`{ "description": "something went wrong!" }`))
 		})
 
+		When("catalog validation is enabled", func() {
+			It("logs nothing when the catalog is valid", func() {
+				brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithCatalogValidation())
+				Expect(logBuffer.Contents()).To(BeEmpty())
+			})
+
+			It("logs each problem with the catalog", func() {
+				fakeServiceBroker.PlanID = ""
+				brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithCatalogValidation())
+				Expect(lastLogLine()).To(SatisfyAll(
+					HaveKeyWithValue("msg", "catalog-validation.invalid-catalog"),
+					HaveKeyWithValue("path", "services[0].plans[0].id"),
+					HaveKeyWithValue("error", "must not be empty"),
+				))
+			})
+		})
+
 		Context("the request is malformed", func() {
 			It("missing header X-Broker-API-Version", func() {
 				response := makeCatalogRequest("", false)
@@ -679,6 +989,10 @@ var _ = Describe("Service Broker API", func() {
 
 			BeforeEach(func() {
 				instanceID = uniqueInstanceID()
+				fakeServiceBroker.MaintenanceInfo = &brokerapi.MaintenanceInfo{
+					Public:  map[string]string{"k8s-version": "0.0.1-alpha2"},
+					Private: "just a sha thing",
+				}
 				provisionDetails = map[string]any{
 					"service_id":        fakeServiceBroker.ServiceID,
 					"plan_id":           "plan-id",
@@ -735,6 +1049,7 @@ var _ = Describe("Service Broker API", func() {
 						OperationDataToReturn: "some-operation-data",
 						ServiceID:             fakeServiceBroker.ServiceID,
 						PlanID:                fakeServiceBroker.PlanID,
+						MaintenanceInfo:       fakeServiceBroker.MaintenanceInfo,
 					}
 					fakeAsyncServiceBroker := &fakes.FakeAsyncServiceBroker{
 						FakeServiceBroker:    *fakeServiceBroker,
@@ -800,6 +1115,111 @@ var _ = Describe("Service Broker API", func() {
 				})
 			})
 
+			When("the maintenance_info does not match the catalog", func() {
+				BeforeEach(func() {
+					provisionDetails["maintenance_info"] = map[string]any{"public": map[string]string{"name": "bar"}}
+				})
+
+				It("returns a 422 without calling Provision", func() {
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusUnprocessableEntity))
+					Expect(readBody(response)).To(MatchJSON(`{
+						"error": "MaintenanceInfoConflict",
+						"description": "passed maintenance_info does not match the catalog maintenance_info"
+					}`))
+					Expect(fakeServiceBroker.ProvisionedInstances).NotTo(HaveKey(instanceID))
+					Expect(lastLogLine()).To(HaveKeyWithValue("msg", "provision.maintenance-info-conflict"))
+				})
+
+				It("calls Provision when the check is disabled", func() {
+					brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBrokerCredentials(credentials), brokerapi.WithoutMaintenanceInfoCheck())
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+					Expect(fakeServiceBroker.ProvisionedInstances).To(HaveKey(instanceID))
+				})
+			})
+
+			When("parameter validation is enabled", func() {
+				BeforeEach(func() {
+					brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBrokerCredentials(credentials), brokerapi.WithParameterValidation())
+				})
+
+				It("calls Provision when the parameters conform to the plan schema", func() {
+					provisionDetails["parameters"] = map[string]any{"billing-account": "some-account"}
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+					Expect(fakeServiceBroker.ProvisionedInstances).To(HaveKey(instanceID))
+				})
+
+				It("calls Provision when there are no parameters", func() {
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+				})
+
+				It("rejects parameters that do not conform to the plan schema", func() {
+					provisionDetails["parameters"] = map[string]any{"billing-account": 42}
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+
+					Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+					Expect(readBody(response)).To(MatchJSON(`{
+						"description": "parameters do not conform to the plan schema",
+						"violations": [{"path": "/billing-account", "keyword": "type", "message": "must be string, but is integer"}]
+					}`))
+					Expect(fakeServiceBroker.ProvisionedInstances).NotTo(HaveKey(instanceID))
+					Expect(lastLogLine()).To(HaveKeyWithValue("msg", "provision.invalid-parameters"))
+				})
+
+				It("rejects numbers that are too large to validate", func() {
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+					provisionDetails["parameters"] = map[string]any{"billing-account": json.Number("1e1000000")}
+					response = makeInstanceProvisioningRequest("another-instance", provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+					Expect(readBody(response)).To(ContainSubstring("numbers must have at most 1000 characters"))
+					Expect(fakeServiceBroker.ProvisionedInstances).NotTo(HaveKey("another-instance"))
+				})
+
+				It("rejects parameters that are too large to validate", func() {
+					provisionDetails["parameters"] = map[string]any{"billing-account": strings.Repeat("a", 256<<10)}
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+					Expect(readBody(response)).To(ContainSubstring("parameters must be at most 262144 bytes"))
+					Expect(fakeServiceBroker.ProvisionedInstances).NotTo(HaveKey(instanceID))
+				})
+			})
+
+			When("strict decoding is enabled", func() {
+				BeforeEach(func() {
+					brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBrokerCredentials(credentials), brokerapi.WithStrictDecoding(0))
+				})
+
+				It("calls Provision when the request has only known fields", func() {
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+					Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+					Expect(fakeServiceBroker.ProvisionedInstances).To(HaveKey(instanceID))
+				})
+
+				It("rejects a request with an unknown field", func() {
+					provisionDetails["space"] = "space-guid"
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+
+					Expect(response).To(HaveHTTPStatus(http.StatusUnprocessableEntity))
+					Expect(readBody(response)).To(ContainSubstring(`unknown field \"space\" at line 1`))
+					Expect(fakeServiceBroker.ProvisionedInstances).NotTo(HaveKey(instanceID))
+					Expect(lastLogLine()).To(HaveKeyWithValue("msg", "provision.invalid-service-details"))
+				})
+
+				It("rejects parameters that are not a JSON object", func() {
+					provisionDetails["parameters"] = "billing-account"
+					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
+
+					Expect(response).To(HaveHTTPStatus(http.StatusUnprocessableEntity))
+					Expect(readBody(response)).To(ContainSubstring("The format of the parameters is not valid JSON"))
+					Expect(lastLogLine()).To(HaveKeyWithValue("msg", "provision.invalid-raw-params"))
+				})
+			})
+
 			Context("when the instance does not exist", func() {
 				It("returns a 201 with empty JSON", func() {
 					response := makeInstanceProvisioningRequest(instanceID, provisionDetails, "")
@@ -1021,6 +1441,7 @@ var _ = Describe("Service Broker API", func() {
 								InstanceLimit:        3,
 								ServiceID:            fakeServiceBroker.ServiceID,
 								PlanID:               fakeServiceBroker.PlanID,
+								MaintenanceInfo:      fakeServiceBroker.MaintenanceInfo,
 							}
 							fakeAsyncServiceBroker := &fakes.FakeAsyncServiceBroker{
 								FakeServiceBroker:    *fakeServiceBroker,
@@ -1043,6 +1464,7 @@ var _ = Describe("Service Broker API", func() {
 								InstanceLimit:        3,
 								ServiceID:            fakeServiceBroker.ServiceID,
 								PlanID:               fakeServiceBroker.PlanID,
+								MaintenanceInfo:      fakeServiceBroker.MaintenanceInfo,
 							}
 							fakeAsyncServiceBroker := &fakes.FakeAsyncServiceBroker{
 								FakeServiceBroker:    *fakeServiceBroker,
@@ -1072,6 +1494,7 @@ var _ = Describe("Service Broker API", func() {
 								InstanceLimit:        3,
 								ServiceID:            fakeServiceBroker.ServiceID,
 								PlanID:               fakeServiceBroker.PlanID,
+								MaintenanceInfo:      fakeServiceBroker.MaintenanceInfo,
 							}
 							fakeAsyncServiceBroker := &fakes.FakeAsyncOnlyServiceBroker{
 								FakeServiceBroker: *fakeServiceBroker,
@@ -1102,6 +1525,7 @@ var _ = Describe("Service Broker API", func() {
 								InstanceLimit:        3,
 								ServiceID:            fakeServiceBroker.ServiceID,
 								PlanID:               fakeServiceBroker.PlanID,
+								MaintenanceInfo:      fakeServiceBroker.MaintenanceInfo,
 							}
 							fakeAsyncServiceBroker := &fakes.FakeAsyncOnlyServiceBroker{
 								FakeServiceBroker: *fakeServiceBroker,
@@ -1220,9 +1644,13 @@ var _ = Describe("Service Broker API", func() {
 
 			BeforeEach(func() {
 				instanceID = uniqueInstanceID()
+				fakeServiceBroker.MaintenanceInfo = &brokerapi.MaintenanceInfo{
+					Public:  map[string]string{"k8s-version": "0.0.1-alpha2"},
+					Private: "just a sha thing",
+				}
 				details = map[string]any{
-					"service_id": "some-service-id",
-					"plan_id":    "new-plan",
+					"service_id": fakeServiceBroker.ServiceID,
+					"plan_id":    fakeServiceBroker.PlanID,
 					"parameters": map[string]any{
 						"new-param": "new-param-value",
 					},
@@ -1282,6 +1710,46 @@ var _ = Describe("Service Broker API", func() {
 				})
 			})
 
+			When("parameter validation is enabled", func() {
+				BeforeEach(func() {
+					brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBrokerCredentials(credentials), brokerapi.WithParameterValidation())
+					details["service_id"] = fakeServiceBroker.ServiceID
+					details["plan_id"] = fakeServiceBroker.PlanID
+					details["parameters"] = map[string]any{"billing-account": true}
+				})
+
+				It("rejects parameters that do not conform to the plan schema", func() {
+					Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+					Expect(readBody(response)).To(MatchJSON(`{
+						"description": "parameters do not conform to the plan schema",
+						"violations": [{"path": "/billing-account", "keyword": "type", "message": "must be string, but is boolean"}]
+					}`))
+					Expect(fakeServiceBroker.UpdatedInstanceIDs).NotTo(ContainElement(instanceID))
+				})
+
+				When("the plan is not changing", func() {
+					BeforeEach(func() {
+						delete(details, "plan_id")
+						details["previous_values"] = map[string]any{"plan_id": fakeServiceBroker.PlanID}
+					})
+
+					It("validates against the schema of the current plan", func() {
+						Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+					})
+				})
+
+				When("there are no parameters", func() {
+					BeforeEach(func() {
+						delete(details, "parameters")
+					})
+
+					It("calls Update", func() {
+						Expect(response).To(HaveHTTPStatus(http.StatusOK))
+						Expect(fakeServiceBroker.UpdatedInstanceIDs).To(ContainElement(instanceID))
+					})
+				})
+			})
+
 			Context("when the broker returns no error", func() {
 				Context("when the broker responds synchronously", func() {
 					It("returns HTTP 200", func() {
@@ -1293,8 +1761,8 @@ var _ = Describe("Service Broker API", func() {
 
 					It("calls broker with instanceID and update details", func() {
 						Expect(fakeServiceBroker.UpdatedInstanceIDs).To(ConsistOf(instanceID))
-						Expect(fakeServiceBroker.UpdateDetails.ServiceID).To(Equal("some-service-id"))
-						Expect(fakeServiceBroker.UpdateDetails.PlanID).To(Equal("new-plan"))
+						Expect(fakeServiceBroker.UpdateDetails.ServiceID).To(Equal(fakeServiceBroker.ServiceID))
+						Expect(fakeServiceBroker.UpdateDetails.PlanID).To(Equal(fakeServiceBroker.PlanID))
 						Expect(fakeServiceBroker.UpdateDetails.PreviousValues).To(Equal(brokerapi.PreviousValues{
 							PlanID:    "old-plan",
 							ServiceID: "service-id",
@@ -1898,8 +2366,8 @@ var _ = Describe("Service Broker API", func() {
 				bindingID = uniqueBindingID()
 				details = map[string]any{
 					"app_guid":   "app_guid",
-					"plan_id":    "plan_id",
-					"service_id": "service_id",
+					"plan_id":    fakeServiceBroker.PlanID,
+					"service_id": fakeServiceBroker.ServiceID,
 					"parameters": map[string]any{
 						"new-param": "new-param-value",
 					},
@@ -1914,8 +2382,8 @@ var _ = Describe("Service Broker API", func() {
 					Expect(ok).To(BeTrue())
 					Expect(fakeServiceBroker.BoundBindings[bindingID]).To(Equal(brokerapi.BindDetails{
 						AppGUID:       "app_guid",
-						PlanID:        "plan_id",
-						ServiceID:     "service_id",
+						PlanID:        fakeServiceBroker.PlanID,
+						ServiceID:     fakeServiceBroker.ServiceID,
 						RawParameters: json.RawMessage(`{"new-param":"new-param-value"}`),
 					}))
 				})
@@ -1935,6 +2403,31 @@ var _ = Describe("Service Broker API", func() {
 					Expect(readBody(response)).To(MatchJSON(fixture("binding.json")))
 				})
 
+				When("parameter validation is enabled", func() {
+					BeforeEach(func() {
+						brokerAPI = brokerapi.NewWithOptions(fakeServiceBroker, brokerLogger, brokerapi.WithBrokerCredentials(credentials), brokerapi.WithParameterValidation())
+					})
+
+					It("calls Bind when the parameters conform to the plan schema", func() {
+						details["parameters"] = map[string]any{"billing-account": "some-account"}
+						response := makeBindingRequest(instanceID, bindingID, details)
+						Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+						Expect(fakeServiceBroker.BoundBindings).To(HaveKey(bindingID))
+					})
+
+					It("rejects parameters that do not conform to the plan schema", func() {
+						details["parameters"] = []string{"billing-account"}
+						response := makeBindingRequest(instanceID, bindingID, details)
+
+						Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+						Expect(readBody(response)).To(MatchJSON(`{
+							"description": "parameters do not conform to the plan schema",
+							"violations": [{"path": "", "keyword": "type", "message": "must be object, but is array"}]
+						}`))
+						Expect(fakeServiceBroker.BoundBindings).NotTo(HaveKey(bindingID))
+					})
+				})
+
 				Context("when syslog_drain_url is being passed", func() {
 					BeforeEach(func() {
 						fakeServiceBroker.SyslogDrainURL = "some-drain-url"
@@ -2406,7 +2899,7 @@ var _ = Describe("Service Broker API", func() {
 					BeforeEach(func() {
 						bindingID = uniqueBindingID()
 						makeBindingRequest(instanceID, bindingID, map[string]any{
-							"service_id": "service_id", "plan_id": "plan_id",
+							"service_id": fakeServiceBroker.ServiceID, "plan_id": fakeServiceBroker.PlanID,
 						})
 					})
 
@@ -2791,3 +3284,11 @@ func withServer(handler http.Handler, callback func(requester)) {
 		return must(server.Client().Do(request))
 	})
 }
+
+func signHS256Token(secret []byte, claims map[string]any) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString(must(json.Marshal(claims)))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -18,71 +18,193 @@ package auth
 import (
 	"crypto/sha256"
 	"crypto/subtle"
+	"errors"
 	"net/http"
+	"time"
 )
 
 type Wrapper struct {
-	credentials []Credential
+	provider CredentialProvider
+	lockout  *Lockout
+}
+
+// CredentialProvider supplies the credentials accepted by a Wrapper. It is consulted on every request,
+// so an implementation may change the credentials while the broker is running, for example to
+// rotate a password without a restart.
+type CredentialProvider interface {
+	Credentials() []Credential
 }
 
 type Credential struct {
-	username []byte
-	password []byte
+	username  []byte
+	password  []byte
+	hash      *verifiedHash
+	expiresAt time.Time
+	label     string
+	scope     *Scope
+}
+
+// NewCredential creates a Credential from a username and plaintext password
+func NewCredential(username, password string) Credential {
+	u := sha256.Sum256([]byte(username))
+	p := sha256.Sum256([]byte(password))
+	return Credential{username: u[:], password: p[:]}
+}
+
+// WithExpiry returns a copy of the Credential that is no longer accepted after the specified time.
+// This allows old and new credentials to overlap during a rotation.
+func (c Credential) WithExpiry(expiresAt time.Time) Credential {
+	c.expiresAt = expiresAt
+	return c
+}
+
+// WithLabel returns a copy of the Credential with a label, which is reported in the Principal
+// when the credential is used. This can identify which platform a request came from.
+func (c Credential) WithLabel(label string) Credential {
+	c.label = label
+	return c
+}
+
+// WithScope returns a copy of the Credential that is restricted to the operations, services
+// and plans in the Scope. The Scope is reported in the Principal, and enforced by the APIHandler.
+func (c Credential) WithScope(scope Scope) Credential {
+	c.scope = &scope
+	return c
+}
+
+// StaticCredentials is a CredentialProvider for a fixed set of credentials
+type StaticCredentials []Credential
+
+func (s StaticCredentials) Credentials() []Credential {
+	return s
 }
 
 func NewWrapperMultiple(users map[string]string) *Wrapper {
-	var cs []Credential
+	var cs StaticCredentials
 	for k, v := range users {
-		u := sha256.Sum256([]byte(k))
-		p := sha256.Sum256([]byte(v))
-		cs = append(cs, Credential{username: u[:], password: p[:]})
+		cs = append(cs, NewCredential(k, v))
 	}
-	return &Wrapper{credentials: cs}
+	return NewWrapperWithProvider(cs)
 }
 
 func NewWrapper(username, password string) *Wrapper {
 	return NewWrapperMultiple(map[string]string{username: password})
 }
 
+// NewWrapperWithProvider creates a Wrapper which accepts whichever credentials
+// the CredentialProvider returns at the time of each request
+func NewWrapperWithProvider(provider CredentialProvider) *Wrapper {
+	return &Wrapper{provider: provider}
+}
+
 const notAuthorized = "Not Authorized"
 
 func (wrapper *Wrapper) Wrap(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !authorized(wrapper, r) {
-			http.Error(w, notAuthorized, http.StatusUnauthorized)
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			respondBasicFailure(w, err)
 			return
 		}
 
-		handler.ServeHTTP(w, r)
+		handler.ServeHTTP(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
 	})
 }
 
 func (wrapper *Wrapper) WrapFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !authorized(wrapper, r) {
-			http.Error(w, notAuthorized, http.StatusUnauthorized)
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			respondBasicFailure(w, err)
 			return
 		}
 
-		handlerFunc(w, r)
+		handlerFunc(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	}
+}
+
+// Authenticate checks the basic authentication credentials of the request
+func (wrapper *Wrapper) Authenticate(r *http.Request) (*Principal, error) {
+	if _, _, ok := r.BasicAuth(); !ok {
+		return nil, errors.New("no basic authentication credentials")
+	}
+
+	attempt, err := wrapper.lockout.check(r)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, ok := authorized(wrapper, r)
+	if !ok {
+		attempt.failure()
+		return nil, errors.New("invalid basic authentication credentials")
 	}
+	attempt.success()
+
+	return principal, nil
+}
+
+func (wrapper *Wrapper) Challenge() string {
+	return `Basic realm="service-broker"`
 }
 
-func authorized(wrapper *Wrapper, r *http.Request) bool {
+func respondBasicFailure(w http.ResponseWriter, err error) {
+	var lockedOut *lockedOutError
+	if errors.As(err, &lockedOut) {
+		lockedOut.respond(w)
+		return
+	}
+
+	http.Error(w, notAuthorized, http.StatusUnauthorized)
+}
+
+func authorized(wrapper *Wrapper, r *http.Request) (*Principal, bool) {
 	username, password, isOk := r.BasicAuth()
-	if isOk {
-		u := sha256.Sum256([]byte(username))
-		p := sha256.Sum256([]byte(password))
-		for _, c := range wrapper.credentials {
-			if c.isAuthorized(u, p) {
-				return true
-			}
+	if !isOk {
+		return nil, false
+	}
+
+	u := sha256.Sum256([]byte(username))
+	p := sha256.Sum256([]byte(password))
+	now := time.Now()
+
+	var dummy passwordHash
+	hashed := false
+	for _, c := range wrapper.provider.Credentials() {
+		if c.hash != nil {
+			dummy = c.hash.passwordHash
 		}
+
+		authorized, checkedHash := c.isAuthorized(u, p, password)
+		hashed = hashed || checkedHash
+		if authorized && !c.expired(now) {
+			return &Principal{Name: username, Scheme: SchemeBasic, Label: c.label, Scope: c.scope}, true
+		}
+	}
+
+	// When no hashed credential has the username, a hash is still verified so that the time taken
+	// does not reveal whether the username exists
+	if !hashed && dummy != nil {
+		verifyPassword(dummy, password)
+	}
+	return nil, false
+}
+
+// isAuthorized checks the username and password against the credential, and reports whether a password
+// hash was verified. Hashed credentials are only checked once the username matches, as hashing is
+// deliberately slow.
+func (c Credential) isAuthorized(uChecksum [32]byte, pChecksum [32]byte, password string) (authorized, hashed bool) {
+	if subtle.ConstantTimeCompare(c.username, uChecksum[:]) != 1 {
+		return false, false
 	}
-	return false
+
+	if c.hash != nil {
+		return c.hash.matches(uChecksum, password), true
+	}
+
+	return subtle.ConstantTimeCompare(c.password, pChecksum[:]) == 1, false
 }
 
-func (c Credential) isAuthorized(uChecksum [32]byte, pChecksum [32]byte) bool {
-	return subtle.ConstantTimeCompare(c.username, uChecksum[:]) == 1 &&
-		subtle.ConstantTimeCompare(c.password, pChecksum[:]) == 1
+func (c Credential) expired(now time.Time) bool {
+	return !c.expiresAt.IsZero() && now.After(c.expiresAt)
 }
@@ -165,4 +165,41 @@ var _ = Describe("Auth Wrapper", func() {
 			Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
 		})
 	})
+
+	Describe("principal", func() {
+		It("adds the principal for the matched credential to the request context", func() {
+			var principal *auth.Principal
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = auth.RetrievePrincipalFromContext(r.Context())
+				w.WriteHeader(http.StatusCreated)
+			})
+			wrapper := auth.NewWrapperWithProvider(auth.StaticCredentials{
+				auth.NewCredential("platform-a", "password-a").WithLabel("cf-production"),
+				auth.NewCredential("platform-b", "password-b").WithLabel("k8s-staging"),
+			})
+
+			wrapper.Wrap(handler).ServeHTTP(httpRecorder, newRequest("platform-b", "password-b"))
+			Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+			Expect(principal).To(Equal(&auth.Principal{
+				Name:   "platform-b",
+				Scheme: auth.SchemeBasic,
+				Label:  "k8s-staging",
+			}))
+		})
+
+		It("adds the principal when wrapping a handlerFunc", func() {
+			var principal *auth.Principal
+			handlerFunc := auth.NewWrapper(username, password).WrapFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = auth.RetrievePrincipalFromContext(r.Context())
+			})
+
+			handlerFunc.ServeHTTP(httpRecorder, newRequest(username, password))
+			Expect(principal).To(Equal(&auth.Principal{Name: username, Scheme: auth.SchemeBasic}))
+		})
+
+		It("has no principal when the context was not authenticated", func() {
+			request := newRequest(username, password)
+			Expect(auth.RetrievePrincipalFromContext(request.Context())).To(BeNil())
+		})
+	})
 })
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Authenticator authenticates requests using a single scheme. Authenticate returns the Principal
+// for a request, or an error if the request is not authenticated by this scheme. Challenge returns
+// the WWW-Authenticate challenge for the scheme, or an empty string if there is none.
+// Wrapper, BearerWrapper, ClientCertWrapper and HMACWrapper are all Authenticators.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+	Challenge() string
+}
+
+var (
+	_ Authenticator = (*Wrapper)(nil)
+	_ Authenticator = (*BearerWrapper)(nil)
+	_ Authenticator = (*ClientCertWrapper)(nil)
+	_ Authenticator = (*HMACWrapper)(nil)
+	_ Authenticator = (*AnyWrapper)(nil)
+)
+
+type authenticatorFunc struct {
+	challenge    string
+	authenticate func(*http.Request) (*Principal, error)
+}
+
+// NewAuthenticator creates an Authenticator for a custom scheme from a function. The function
+// should set Principal.Scheme so that the scheme is recorded when it authenticates a request.
+func NewAuthenticator(challenge string, authenticate func(r *http.Request) (*Principal, error)) Authenticator {
+	return authenticatorFunc{challenge: challenge, authenticate: authenticate}
+}
+
+func (a authenticatorFunc) Authenticate(r *http.Request) (*Principal, error) {
+	return a.authenticate(r)
+}
+
+func (a authenticatorFunc) Challenge() string {
+	return a.challenge
+}
+
+// AnyWrapper accepts a request if any one of its Authenticators authenticates it. They are tried
+// in order, and the Principal from the first to succeed is added to the request context.
+// If none succeed, the response is 401 Unauthorized with a WWW-Authenticate challenge for
+// each scheme, unless a Lockout has rejected the request, in which case it is 429 Too Many Requests,
+// or the body was too large to check an HMAC signature, in which case it is 413 Request Entity Too Large.
+type AnyWrapper struct {
+	authenticators []Authenticator
+}
+
+func Any(authenticators ...Authenticator) *AnyWrapper {
+	return &AnyWrapper{authenticators: authenticators}
+}
+
+func (wrapper *AnyWrapper) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			wrapper.respondFailure(w, err)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	})
+}
+
+func (wrapper *AnyWrapper) WrapFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			wrapper.respondFailure(w, err)
+			return
+		}
+
+		handlerFunc(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	}
+}
+
+// Authenticate tries each Authenticator in turn, returning the first Principal, or all the errors
+func (wrapper *AnyWrapper) Authenticate(r *http.Request) (*Principal, error) {
+	var errs []error
+	for _, a := range wrapper.authenticators {
+		principal, err := a.Authenticate(r)
+		if err == nil {
+			if principal == nil {
+				principal = &Principal{}
+			}
+			return principal, nil
+		}
+		errs = append(errs, err)
+
+		// The body has been partly read, so the request cannot be authenticated by another scheme
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("no authenticators configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Challenge is the challenges of all the Authenticators, separated by commas
+func (wrapper *AnyWrapper) Challenge() string {
+	return strings.Join(wrapper.challenges(), ", ")
+}
+
+func (wrapper *AnyWrapper) challenges() []string {
+	var challenges []string
+	for _, a := range wrapper.authenticators {
+		if c := a.Challenge(); c != "" {
+			challenges = append(challenges, c)
+		}
+	}
+	return challenges
+}
+
+func (wrapper *AnyWrapper) respondFailure(w http.ResponseWriter, err error) {
+	var lockedOut *lockedOutError
+	if errors.As(err, &lockedOut) {
+		lockedOut.respond(w)
+		return
+	}
+	if respondBodyTooLarge(w, err) {
+		return
+	}
+
+	for _, c := range wrapper.challenges() {
+		w.Header().Add("WWW-Authenticate", c)
+	}
+	http.Error(w, notAuthorized, http.StatusUnauthorized)
+}
@@ -0,0 +1,129 @@
+package auth_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("Any", func() {
+	var (
+		secret       []byte
+		principal    *auth.Principal
+		httpRecorder *httptest.ResponseRecorder
+		handler      http.Handler
+		lockout      *auth.Lockout
+	)
+
+	newRequest := func() *http.Request {
+		request := must(http.NewRequest("GET", "", nil))
+		request.RemoteAddr = "10.0.0.1:12345"
+		return request
+	}
+
+	BeforeEach(func() {
+		secret = []byte("bearer-secret")
+		principal = nil
+		httpRecorder = httptest.NewRecorder()
+		lockout = auth.NewLockout(auth.LockoutConfig{MaxAttempts: 1, Logger: discardLogger()})
+	})
+
+	JustBeforeEach(func() {
+		keys := auth.NewKeySet()
+		Expect(keys.Add("", secret)).To(Succeed())
+
+		handler = auth.Any(
+			auth.NewWrapper("broker", "password").WithLockout(lockout),
+			auth.NewBearerWrapper(auth.BearerConfig{Keys: keys}),
+			auth.NewAuthenticator(`ApiKey realm="broker"`, func(r *http.Request) (*auth.Principal, error) {
+				if r.Header.Get("X-Api-Key") == "key" {
+					return &auth.Principal{Name: "automation", Scheme: "api-key"}, nil
+				}
+				return nil, errors.New("no API key")
+			}),
+		).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = auth.RetrievePrincipalFromContext(r.Context())
+			w.WriteHeader(http.StatusCreated)
+		}))
+	})
+
+	It("accepts basic authentication", func() {
+		request := newRequest()
+		request.SetBasicAuth("broker", "password")
+		handler.ServeHTTP(httpRecorder, request)
+
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		Expect(principal.Scheme).To(Equal(auth.SchemeBasic))
+	})
+
+	It("accepts a bearer token", func() {
+		request := newRequest()
+		request.Header.Set("Authorization", "Bearer "+signHS256(secret, "", map[string]any{"sub": "platform", "exp": time.Now().Add(time.Hour).Unix()}))
+		handler.ServeHTTP(httpRecorder, request)
+
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		Expect(principal).To(Equal(&auth.Principal{Name: "platform", Scheme: auth.SchemeBearer}))
+	})
+
+	It("accepts a custom scheme", func() {
+		request := newRequest()
+		request.Header.Set("X-Api-Key", "key")
+		handler.ServeHTTP(httpRecorder, request)
+
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		Expect(principal).To(Equal(&auth.Principal{Name: "automation", Scheme: "api-key"}))
+	})
+
+	It("challenges for every scheme when no scheme succeeds", func() {
+		handler.ServeHTTP(httpRecorder, newRequest())
+
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+		Expect(httpRecorder.Header().Values("WWW-Authenticate")).To(Equal([]string{
+			`Basic realm="service-broker"`,
+			"Bearer",
+			`ApiKey realm="broker"`,
+		}))
+	})
+
+	It("does not count other schemes as failed basic authentication", func() {
+		for range 3 {
+			request := newRequest()
+			request.Header.Set("X-Api-Key", "key")
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+		}
+
+		request := newRequest()
+		request.SetBasicAuth("broker", "password")
+		handler.ServeHTTP(httpRecorder, request)
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("responds with 429 when basic authentication is locked out", func() {
+		request := newRequest()
+		request.SetBasicAuth("broker", "wrong")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		request = newRequest()
+		request.SetBasicAuth("broker", "password")
+		handler.ServeHTTP(httpRecorder, request)
+		Expect(httpRecorder.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(httpRecorder.Header().Get("Retry-After")).To(Equal("1"))
+	})
+
+	It("reports the challenges of all schemes", func() {
+		Expect(auth.Any(auth.NewWrapper("a", "b"), auth.NewHMACWrapper(auth.HMACConfig{})).Challenge()).
+			To(Equal(`Basic realm="service-broker", HMAC-SHA256`))
+	})
+})
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
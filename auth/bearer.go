@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BearerConfig configures a BearerWrapper. Keys is required. When Issuer or Audience
+// are set, the corresponding "iss" or "aud" claim must match. Leeway is the clock skew
+// allowed when checking the "exp" and "nbf" claims.
+type BearerConfig struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+// BearerWrapper authenticates requests carrying a signed JWT (RS256, ES256 or HS256)
+// in a "Bearer" Authorization header. Tokens are verified locally against the configured
+// key set, so no identity provider needs to be reachable.
+type BearerWrapper struct {
+	config BearerConfig
+}
+
+func NewBearerWrapper(config BearerConfig) *BearerWrapper {
+	return &BearerWrapper{config: config}
+}
+
+var errNoBearerToken = errors.New("no bearer token")
+
+// bearerChallenge returns the WWW-Authenticate challenge for a request that failed authentication. As in
+// RFC 6750 section 3.1, the invalid_token error is only sent when a token was sent and rejected.
+func bearerChallenge(err error) string {
+	if errors.Is(err, errNoBearerToken) {
+		return "Bearer"
+	}
+	return `Bearer error="invalid_token"`
+}
+
+func (wrapper *BearerWrapper) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", bearerChallenge(err))
+			http.Error(w, notAuthorized, http.StatusUnauthorized)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	})
+}
+
+func (wrapper *BearerWrapper) WrapFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", bearerChallenge(err))
+			http.Error(w, notAuthorized, http.StatusUnauthorized)
+			return
+		}
+
+		handlerFunc(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer    string      `json:"iss"`
+	Subject   string      `json:"sub"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt *float64    `json:"exp"`
+	NotBefore *float64    `json:"nbf"`
+}
+
+// jwtAudience handles the "aud" claim, which may be either a string or an array of strings
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return errors.New("aud claim must be a string or an array of strings")
+	}
+	*a = multiple
+	return nil
+}
+
+func (wrapper *BearerWrapper) Challenge() string {
+	return "Bearer"
+}
+
+// Authenticate verifies the bearer token of the request
+func (wrapper *BearerWrapper) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, errNoBearerToken
+	}
+
+	header, claims, err := wrapper.verifyToken(strings.TrimSpace(token), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{Name: claims.Subject, Scheme: SchemeBearer, Label: header.Kid}, nil
+}
+
+func (wrapper *BearerWrapper) verifyToken(token string, now time.Time) (jwtHeader, jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtHeader{}, jwtClaims{}, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return jwtHeader{}, jwtClaims{}, fmt.Errorf("malformed token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return jwtHeader{}, jwtClaims{}, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	if !verifySignature(header.Alg, wrapper.config.Keys.candidates(header.Kid), parts[0]+"."+parts[1], signature) {
+		return jwtHeader{}, jwtClaims{}, errors.New("invalid token signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return jwtHeader{}, jwtClaims{}, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	if err := wrapper.validateClaims(claims, now); err != nil {
+		return jwtHeader{}, jwtClaims{}, err
+	}
+
+	return header, claims, nil
+}
+
+func (wrapper *BearerWrapper) validateClaims(claims jwtClaims, now time.Time) error {
+	leeway := wrapper.config.Leeway
+
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no exp claim")
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(leeway)) {
+		return errors.New("token has expired")
+	}
+
+	if claims.NotBefore != nil && now.Add(leeway).Before(unixTime(*claims.NotBefore)) {
+		return errors.New("token is not valid yet")
+	}
+
+	if wrapper.config.Issuer != "" && claims.Issuer != wrapper.config.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+
+	if wrapper.config.Audience != "" && !slices.Contains(claims.Audience, wrapper.config.Audience) {
+		return errors.New("token is not intended for this audience")
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, keys []any, signingInput string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	for _, key := range keys {
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			if alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if alg == "ES256" && len(signature) == 64 {
+				r := new(big.Int).SetBytes(signature[:32])
+				s := new(big.Int).SetBytes(signature[32:])
+				if ecdsa.Verify(key, digest[:], r, s) {
+					return true
+				}
+			}
+		case []byte:
+			if alg == "HS256" {
+				mac := hmac.New(sha256.New, key)
+				mac.Write([]byte(signingInput))
+				if hmac.Equal(mac.Sum(nil), signature) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second)))
+}
@@ -0,0 +1,361 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("Bearer Wrapper", func() {
+	const (
+		issuer   = "https://uaa.example.com/oauth/token"
+		audience = "service-broker"
+	)
+
+	var (
+		rsaKey         *rsa.PrivateKey
+		ecKey          *ecdsa.PrivateKey
+		hmacSecret     []byte
+		keySet         *auth.KeySet
+		config         auth.BearerConfig
+		httpRecorder   *httptest.ResponseRecorder
+		wrappedHandler http.Handler
+	)
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss": issuer,
+			"aud": []string{"other", audience},
+			"sub": "platform-client",
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"nbf": time.Now().Add(-time.Minute).Unix(),
+		}
+	}
+
+	newRequest := func(token string) *http.Request {
+		request, err := http.NewRequest("GET", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.Header.Set("Authorization", "Bearer "+token)
+		return request
+	}
+
+	BeforeEach(func() {
+		var err error
+		rsaKey = testRSAKey()
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		hmacSecret = []byte("a-very-secret-shared-key")
+
+		jwks := map[string]any{
+			"keys": []map[string]any{
+				{
+					"kty": "RSA",
+					"kid": "rsa-key",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+				},
+				{
+					"kty": "EC",
+					"kid": "ec-key",
+					"crv": "P-256",
+					"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+					"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+				},
+				{
+					"kty": "oct",
+					"kid": "hmac-key",
+					"k":   base64.RawURLEncoding.EncodeToString(hmacSecret),
+				},
+				{
+					"kty": "RSA",
+					"kid": "encryption-key",
+					"use": "enc",
+				},
+			},
+		}
+
+		path := filepath.Join(GinkgoT().TempDir(), "jwks.json")
+		Expect(os.WriteFile(path, must(json.Marshal(jwks)), 0o600)).To(Succeed())
+		keySet, err = auth.LoadJWKSFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		config = auth.BearerConfig{
+			Keys:     keySet,
+			Issuer:   issuer,
+			Audience: audience,
+		}
+		httpRecorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		wrappedHandler = auth.NewBearerWrapper(config).Wrap(handler)
+	})
+
+	It("works with a valid RS256 token", func() {
+		token := signRS256(rsaKey, "rsa-key", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("works with a valid ES256 token", func() {
+		token := signES256(ecKey, "ec-key", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("works with a valid HS256 token", func() {
+		token := signHS256(hmacSecret, "hmac-key", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("adds the token subject to the request context", func() {
+		var principal *auth.Principal
+		handler := auth.NewBearerWrapper(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = auth.RetrievePrincipalFromContext(r.Context())
+		}))
+
+		handler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", validClaims())))
+		Expect(principal).To(Equal(&auth.Principal{
+			Name:   "platform-client",
+			Scheme: auth.SchemeBearer,
+			Label:  "rsa-key",
+		}))
+	})
+
+	It("works when the token has no key ID", func() {
+		token := signRS256(rsaKey, "", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("works with a wrapped handlerFunc", func() {
+		handlerFunc := auth.NewBearerWrapper(config).WrapFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		handlerFunc.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", validClaims())))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("fails when there is no token", func() {
+		request, err := http.NewRequest("GET", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		wrappedHandler.ServeHTTP(httpRecorder, request)
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+		Expect(httpRecorder.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+	})
+
+	It("fails when basic auth is used", func() {
+		request, err := http.NewRequest("GET", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.SetBasicAuth("username", "password")
+		wrappedHandler.ServeHTTP(httpRecorder, request)
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the token is malformed", func() {
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest("not-a-token"))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+		Expect(httpRecorder.Header().Get("WWW-Authenticate")).To(Equal(`Bearer error="invalid_token"`))
+	})
+
+	It("fails when the token is signed by an unknown key", func() {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		token := signRS256(otherKey, "rsa-key", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the key ID is not in the key set", func() {
+		token := signRS256(rsaKey, "unknown-key", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the algorithm does not match the key type", func() {
+		token := signHS256(hmacSecret, "rsa-key", validClaims())
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the token is unsigned", func() {
+		token := encodeSegment(map[string]any{"alg": "none"}) + "." + encodeSegment(validClaims()) + "."
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(token))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the token has expired", func() {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the token has no expiry", func() {
+		claims := validClaims()
+		delete(claims, "exp")
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the token is not valid yet", func() {
+		claims := validClaims()
+		claims["nbf"] = time.Now().Add(time.Minute).Unix()
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the issuer is wrong", func() {
+		claims := validClaims()
+		claims["iss"] = "https://evil.example.com"
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the audience is wrong", func() {
+		claims := validClaims()
+		claims["aud"] = "some-other-service"
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	When("leeway is configured", func() {
+		BeforeEach(func() {
+			config.Leeway = 2 * time.Minute
+		})
+
+		It("accepts a recently expired token", func() {
+			claims := validClaims()
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+			Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		})
+	})
+
+	When("no issuer or audience is configured", func() {
+		BeforeEach(func() {
+			config.Issuer = ""
+			config.Audience = ""
+		})
+
+		It("does not check them", func() {
+			claims := validClaims()
+			delete(claims, "iss")
+			delete(claims, "aud")
+			wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", claims)))
+			Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		})
+	})
+
+	When("no key set is configured", func() {
+		BeforeEach(func() {
+			config.Keys = nil
+		})
+
+		It("fails", func() {
+			wrappedHandler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "rsa-key", validClaims())))
+			Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("key sets", func() {
+		It("can be built without a JWKS file", func() {
+			config.Keys = auth.NewKeySet()
+			Expect(config.Keys.Add("local", &rsaKey.PublicKey)).To(Succeed())
+
+			handler := auth.NewBearerWrapper(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			}))
+			handler.ServeHTTP(httpRecorder, newRequest(signRS256(rsaKey, "local", validClaims())))
+			Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects unsupported key types", func() {
+			Expect(auth.NewKeySet().Add("bad", "a string")).To(MatchError(ContainSubstring("unsupported key type")))
+		})
+
+		It("rejects a JWKS with no signing keys", func() {
+			_, err := auth.ParseJWKS([]byte(`{"keys":[]}`))
+			Expect(err).To(MatchError(ContainSubstring("no signing keys")))
+		})
+
+		It("rejects a JWKS with an EC point that is not on the curve", func() {
+			_, err := auth.ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`))
+			Expect(err).To(MatchError(ContainSubstring("not on the P-256 curve")))
+		})
+	})
+})
+
+// RSA key generation is slow, so a single key is shared between tests
+var testRSAKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+})
+
+func encodeSegment(v any) string {
+	return base64.RawURLEncoding.EncodeToString(must(json.Marshal(v)))
+}
+
+func signingInput(alg, kid string, claims map[string]any) string {
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	return encodeSegment(header) + "." + encodeSegment(claims)
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	input := signingInput("RS256", kid, claims)
+	digest := sha256.Sum256([]byte(input))
+	signature := must(rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]))
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	input := signingInput("ES256", kid, claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	Expect(err).NotTo(HaveOccurred())
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signHS256(secret []byte, kid string, claims map[string]any) string {
+	input := signingInput("HS256", kid, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func must[A any](input A, err error) A {
+	GinkgoHelper()
+
+	Expect(err).NotTo(HaveOccurred())
+	return input
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+// ClientCertConfig configures a ClientCertWrapper. CAs is the pool of certificate authorities that
+// client certificates must chain to. If any of the allowlists are populated then the client certificate
+// must also match at least one entry: a subject common name, a DNS SAN, a URI SAN (e.g. a SPIFFE ID),
+// or a SHA-256 fingerprint of the DER encoded certificate (hex, optionally separated by colons).
+//
+// The TLS server must request client certificates, for example by setting
+// tls.Config.ClientAuth to tls.RequireAnyClientCert or tls.VerifyClientCertIfGiven.
+type ClientCertConfig struct {
+	CAs                 *x509.CertPool
+	AllowedCommonNames  []string
+	AllowedDNSNames     []string
+	AllowedURIs         []string
+	AllowedFingerprints []string
+}
+
+// ClientCertWrapper authenticates requests using the client certificate presented during the TLS handshake
+type ClientCertWrapper struct {
+	config       ClientCertConfig
+	fingerprints []string
+}
+
+func NewClientCertWrapper(config ClientCertConfig) *ClientCertWrapper {
+	fingerprints := make([]string, 0, len(config.AllowedFingerprints))
+	for _, f := range config.AllowedFingerprints {
+		fingerprints = append(fingerprints, normalizeFingerprint(f))
+	}
+
+	return &ClientCertWrapper{config: config, fingerprints: fingerprints}
+}
+
+func (wrapper *ClientCertWrapper) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	})
+}
+
+func (wrapper *ClientCertWrapper) WrapFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+
+		handlerFunc(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	}
+}
+
+// Challenge is empty, as there is no WWW-Authenticate challenge for client certificates
+func (wrapper *ClientCertWrapper) Challenge() string {
+	return ""
+}
+
+// Authenticate verifies the client certificate of the request
+func (wrapper *ClientCertWrapper) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, errors.New("no client certificate presented")
+	}
+
+	if wrapper.config.CAs == nil {
+		return nil, errors.New("no certificate authorities configured")
+	}
+
+	leaf := r.TLS.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         wrapper.config.CAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, errors.New("client certificate could not be verified")
+	}
+
+	label, ok := wrapper.allowed(leaf)
+	if !ok {
+		return nil, errors.New("client certificate is not allowed")
+	}
+
+	return &Principal{Name: leaf.Subject.CommonName, Scheme: SchemeClientCert, Label: label}, nil
+}
+
+// allowed checks the certificate against the allowlists, and returns the entry that matched
+func (wrapper *ClientCertWrapper) allowed(cert *x509.Certificate) (string, bool) {
+	c := wrapper.config
+	if len(c.AllowedCommonNames) == 0 && len(c.AllowedDNSNames) == 0 && len(c.AllowedURIs) == 0 && len(wrapper.fingerprints) == 0 {
+		return "", true
+	}
+
+	if cert.Subject.CommonName != "" && slices.Contains(c.AllowedCommonNames, cert.Subject.CommonName) {
+		return cert.Subject.CommonName, true
+	}
+
+	for _, name := range cert.DNSNames {
+		if slices.Contains(c.AllowedDNSNames, name) {
+			return name, true
+		}
+	}
+
+	for _, uri := range cert.URIs {
+		if slices.Contains(c.AllowedURIs, uri.String()) {
+			return uri.String(), true
+		}
+	}
+
+	if f := fingerprint(cert); slices.Contains(wrapper.fingerprints, f) {
+		return f, true
+	}
+
+	return "", false
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeFingerprint(f string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(f), ":", ""))
+}
+
+// respondUnauthorized writes a 401 response with a JSON body in the style of the Open Service Broker API
+func respondUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(apiresponses.ErrorResponse{
+		Error:       "Unauthorized",
+		Description: err.Error(),
+	})
+}
@@ -0,0 +1,214 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("Client Certificate Wrapper", func() {
+	var (
+		ca             *testCA
+		clientCert     *x509.Certificate
+		config         auth.ClientCertConfig
+		httpRecorder   *httptest.ResponseRecorder
+		wrappedHandler http.Handler
+	)
+
+	newRequest := func(certs ...*x509.Certificate) *http.Request {
+		request, err := http.NewRequest("GET", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		if len(certs) > 0 {
+			request.TLS = &tls.ConnectionState{PeerCertificates: certs}
+		}
+		return request
+	}
+
+	BeforeEach(func() {
+		ca = newTestCA("test-ca")
+		clientCert = ca.issue(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: "cloud-controller"},
+			DNSNames:    []string{"cc.service.internal"},
+			URIs:        []*url.URL{must(url.Parse("spiffe://example.org/platform/cc"))},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+
+		config = auth.ClientCertConfig{CAs: ca.pool()}
+		httpRecorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		wrappedHandler = auth.NewClientCertWrapper(config).Wrap(handler)
+	})
+
+	It("works when the certificate chains to the CA", func() {
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(clientCert))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("adds the certificate subject to the request context", func() {
+		config.AllowedURIs = []string{"spiffe://example.org/platform/cc"}
+		var principal *auth.Principal
+		handler := auth.NewClientCertWrapper(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = auth.RetrievePrincipalFromContext(r.Context())
+		}))
+
+		handler.ServeHTTP(httpRecorder, newRequest(clientCert))
+		Expect(principal).To(Equal(&auth.Principal{
+			Name:   "cloud-controller",
+			Scheme: auth.SchemeClientCert,
+			Label:  "spiffe://example.org/platform/cc",
+		}))
+	})
+
+	It("works with a wrapped handlerFunc", func() {
+		handlerFunc := auth.NewClientCertWrapper(config).WrapFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		handlerFunc.ServeHTTP(httpRecorder, newRequest(clientCert))
+		Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("fails with a JSON body when no certificate is presented", func() {
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest())
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+		Expect(httpRecorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(httpRecorder.Body.String()).To(MatchJSON(`{"error":"Unauthorized","description":"no client certificate presented"}`))
+	})
+
+	It("fails when the certificate was issued by another CA", func() {
+		other := newTestCA("other-ca").issue(&x509.Certificate{Subject: pkix.Name{CommonName: "cloud-controller"}})
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(other))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("fails when the certificate is only valid for server authentication", func() {
+		serverCert := ca.issue(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: "cloud-controller"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		})
+		wrappedHandler.ServeHTTP(httpRecorder, newRequest(serverCert))
+		Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	When("no CA pool is configured", func() {
+		BeforeEach(func() {
+			config.CAs = nil
+		})
+
+		It("fails", func() {
+			wrappedHandler.ServeHTTP(httpRecorder, newRequest(clientCert))
+			Expect(httpRecorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	When("an allowlist is configured", func() {
+		DescribeTable("matching",
+			func(update func(*auth.ClientCertConfig), expectedCode int) {
+				update(&config)
+				handler := auth.NewClientCertWrapper(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusCreated)
+				}))
+				handler.ServeHTTP(httpRecorder, newRequest(clientCert))
+				Expect(httpRecorder.Code).To(Equal(expectedCode))
+			},
+			Entry("common name allowed", func(c *auth.ClientCertConfig) {
+				c.AllowedCommonNames = []string{"cloud-controller"}
+			}, http.StatusCreated),
+			Entry("common name not allowed", func(c *auth.ClientCertConfig) {
+				c.AllowedCommonNames = []string{"someone-else"}
+			}, http.StatusUnauthorized),
+			Entry("DNS SAN allowed", func(c *auth.ClientCertConfig) {
+				c.AllowedDNSNames = []string{"cc.service.internal"}
+			}, http.StatusCreated),
+			Entry("DNS SAN not allowed", func(c *auth.ClientCertConfig) {
+				c.AllowedDNSNames = []string{"other.service.internal"}
+			}, http.StatusUnauthorized),
+			Entry("SPIFFE ID allowed", func(c *auth.ClientCertConfig) {
+				c.AllowedURIs = []string{"spiffe://example.org/platform/cc"}
+			}, http.StatusCreated),
+			Entry("SPIFFE ID not allowed", func(c *auth.ClientCertConfig) {
+				c.AllowedURIs = []string{"spiffe://example.org/platform/other"}
+			}, http.StatusUnauthorized),
+			Entry("any entry matches", func(c *auth.ClientCertConfig) {
+				c.AllowedCommonNames = []string{"someone-else"}
+				c.AllowedURIs = []string{"spiffe://example.org/platform/cc"}
+			}, http.StatusCreated),
+		)
+
+		It("matches certificate fingerprints in any common format", func() {
+			sum := sha256.Sum256(clientCert.Raw)
+			hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+			var pairs []string
+			for i := 0; i < len(hexSum); i += 2 {
+				pairs = append(pairs, hexSum[i:i+2])
+			}
+			config.AllowedFingerprints = []string{strings.Join(pairs, ":")}
+
+			handler := auth.NewClientCertWrapper(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			}))
+			handler.ServeHTTP(httpRecorder, newRequest(clientCert))
+			Expect(httpRecorder.Code).To(Equal(http.StatusCreated))
+		})
+	})
+})
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(name string) *testCA {
+	GinkgoHelper()
+
+	key := must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der := must(x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key))
+	return &testCA{cert: must(x509.ParseCertificate(der)), key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *testCA) issue(template *x509.Certificate) *x509.Certificate {
+	GinkgoHelper()
+
+	key := must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	template.SerialNumber = big.NewInt(2)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der := must(x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key))
+	return must(x509.ParseCertificate(der))
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const fileCheckInterval = time.Second
+
+// FileCredentialProvider is a CredentialProvider backed by a JSON file of the form:
+//
+//	{
+//	  "credentials": [
+//	    {"username": "broker", "password": "old-secret", "expires_at": "2025-06-01T00:00:00Z"},
+//	    {"username": "broker", "password_hash": "$2y$10$...", "label": "platform-a"},
+//	    {"username": "staging", "password": "secret", "scope": {"operations": ["catalog", "provision"], "service_ids": ["beta-service"]}}
+//	  ]
+//	}
+//
+// Each credential has either a plaintext "password" or a "password_hash" (see NewHashedCredential()),
+// an optional "label" (see Credential.WithLabel()) and an optional "scope" (see Credential.WithScope()).
+// The file is re-read when its modification time or size changes (checked at most once a second),
+// or on demand by calling Reload() or by sending a signal registered with ReloadOnSignal().
+// If the file cannot be re-read, the previously loaded credentials remain in use, and the error is
+// logged (see WithLogger()) unless it is returned by Reload().
+type FileCredentialProvider struct {
+	path  string
+	parse func([]byte) ([]Credential, error)
+
+	lock        sync.RWMutex
+	logger      *slog.Logger
+	credentials []Credential
+	modTime     time.Time
+	size        int64
+	lastCheck   time.Time
+}
+
+// NewFileCredentialProvider loads credentials from the specified file.
+// An error is returned if the file cannot be read or parsed.
+func NewFileCredentialProvider(path string) (*FileCredentialProvider, error) {
+	p := &FileCredentialProvider{path: path, parse: parseCredentialsFile, logger: slog.Default()}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// WithLogger sets the logger for errors re-reading the file when it changes or a signal is received.
+// The default is slog.Default().
+func (p *FileCredentialProvider) WithLogger(logger *slog.Logger) *FileCredentialProvider {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.logger = logger
+	return p
+}
+
+func (p *FileCredentialProvider) Credentials() []Credential {
+	p.reloadIfChanged()
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.credentials
+}
+
+// Reload re-reads the credentials file
+func (p *FileCredentialProvider) Reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := p.parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing credentials file %q: %w", p.path, err)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.credentials = credentials
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.lastCheck = time.Now()
+	return nil
+}
+
+// ReloadOnSignal re-reads the credentials file whenever one of the signals is received,
+// until the context is cancelled. When no signals are specified, SIGHUP is used.
+func (p *FileCredentialProvider) ReloadOnSignal(ctx context.Context, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				p.reload()
+			}
+		}
+	}()
+}
+
+func (p *FileCredentialProvider) reloadIfChanged() {
+	p.lock.Lock()
+	if time.Since(p.lastCheck) < fileCheckInterval {
+		p.lock.Unlock()
+		return
+	}
+	p.lastCheck = time.Now()
+	modTime, size := p.modTime, p.size
+	p.lock.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil || (info.ModTime().Equal(modTime) && info.Size() == size) {
+		return
+	}
+
+	p.reload()
+}
+
+// reload re-reads the credentials file, logging any error
+func (p *FileCredentialProvider) reload() {
+	if err := p.Reload(); err != nil {
+		p.lock.RLock()
+		logger := p.logger
+		p.lock.RUnlock()
+
+		logger.Error("auth.reload-failed", slog.String("path", p.path), slog.Any("error", err))
+	}
+}
+
+type credentialsFile struct {
+	Credentials []struct {
+		Username     string     `json:"username"`
+		Password     string     `json:"password"`
+		PasswordHash string     `json:"password_hash"`
+		ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+		Label        string     `json:"label"`
+		Scope        *struct {
+			Operations []Operation `json:"operations"`
+			ServiceIDs []string    `json:"service_ids"`
+			PlanIDs    []string    `json:"plan_ids"`
+		} `json:"scope,omitempty"`
+	} `json:"credentials"`
+}
+
+func parseCredentialsFile(data []byte) ([]Credential, error) {
+	var file credentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	credentials := make([]Credential, 0, len(file.Credentials))
+	for i, c := range file.Credentials {
+		var credential Credential
+		switch {
+		case c.Username == "" || (c.Password == "" && c.PasswordHash == ""):
+			return nil, fmt.Errorf("credential %d: username and password must be specified", i)
+		case c.Password != "" && c.PasswordHash != "":
+			return nil, fmt.Errorf("credential %d: only one of password and password_hash may be specified", i)
+		case c.PasswordHash != "":
+			var err error
+			if credential, err = NewHashedCredential(c.Username, c.PasswordHash); err != nil {
+				return nil, fmt.Errorf("credential %d: %w", i, err)
+			}
+		default:
+			credential = NewCredential(c.Username, c.Password)
+		}
+
+		if c.ExpiresAt != nil {
+			credential = credential.WithExpiry(*c.ExpiresAt)
+		}
+		if c.Scope != nil {
+			scope := Scope{Operations: c.Scope.Operations, ServiceIDs: c.Scope.ServiceIDs, PlanIDs: c.Scope.PlanIDs}
+			if err := scope.Validate(); err != nil {
+				return nil, fmt.Errorf("credential %d: %w", i, err)
+			}
+			credential = credential.WithScope(scope)
+		}
+		credentials = append(credentials, credential.WithLabel(c.Label))
+	}
+
+	return credentials, nil
+}
+
+// EnvCredentialProvider is a CredentialProvider that reads credentials from environment variables
+// every time they are requested. The variables are named using a prefix:
+//
+//	<PREFIX>_USERNAME, <PREFIX>_PASSWORD, and optionally <PREFIX>_EXPIRES_AT (RFC 3339) and <PREFIX>_LABEL
+//
+// <PREFIX>_PASSWORD_HASH may be used instead of <PREFIX>_PASSWORD (see NewHashedCredential()), but not as well.
+// A credential can be restricted with comma separated lists in <PREFIX>_OPERATIONS, <PREFIX>_SERVICE_IDS
+// and <PREFIX>_PLAN_IDS (see Credential.WithScope()).
+// Further credentials can be specified with numbered variables, starting at 1, for example:
+//
+//	<PREFIX>_1_USERNAME, <PREFIX>_1_PASSWORD, <PREFIX>_1_EXPIRES_AT, <PREFIX>_1_LABEL
+//
+// A credential with both a password and a password hash, or with an unparsable password hash, expiry time
+// or scope, is ignored.
+type EnvCredentialProvider struct {
+	prefix string
+}
+
+func NewEnvCredentialProvider(prefix string) EnvCredentialProvider {
+	return EnvCredentialProvider{prefix: prefix}
+}
+
+func (p EnvCredentialProvider) Credentials() []Credential {
+	var credentials []Credential
+
+	if c, err := p.lookup(p.prefix); err == nil {
+		credentials = append(credentials, c)
+	}
+
+	for i := 1; ; i++ {
+		c, err := p.lookup(p.prefix + "_" + strconv.Itoa(i))
+		if errors.Is(err, errNoSuchCredential) {
+			break
+		}
+		if err == nil {
+			credentials = append(credentials, c)
+		}
+	}
+
+	return credentials
+}
+
+var errNoSuchCredential = errors.New("no such credential")
+
+func (p EnvCredentialProvider) lookup(prefix string) (Credential, error) {
+	username := os.Getenv(prefix + "_USERNAME")
+	password := os.Getenv(prefix + "_PASSWORD")
+	passwordHash := os.Getenv(prefix + "_PASSWORD_HASH")
+
+	var credential Credential
+	switch {
+	case username == "" || (password == "" && passwordHash == ""):
+		return Credential{}, errNoSuchCredential
+	case password != "" && passwordHash != "":
+		return Credential{}, errors.New("only one of password and password hash may be specified")
+	case passwordHash != "":
+		var err error
+		if credential, err = NewHashedCredential(username, passwordHash); err != nil {
+			return Credential{}, err
+		}
+	default:
+		credential = NewCredential(username, password)
+	}
+
+	if expiresAt := os.Getenv(prefix + "_EXPIRES_AT"); expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return Credential{}, err
+		}
+		credential = credential.WithExpiry(t)
+	}
+
+	operations, serviceIDs, planIDs := splitList(os.Getenv(prefix+"_OPERATIONS")), splitList(os.Getenv(prefix+"_SERVICE_IDS")), splitList(os.Getenv(prefix+"_PLAN_IDS"))
+	if len(operations) > 0 || len(serviceIDs) > 0 || len(planIDs) > 0 {
+		scope := Scope{ServiceIDs: serviceIDs, PlanIDs: planIDs}
+		for _, o := range operations {
+			scope.Operations = append(scope.Operations, Operation(o))
+		}
+		if err := scope.Validate(); err != nil {
+			return Credential{}, err
+		}
+		credential = credential.WithScope(scope)
+	}
+
+	return credential.WithLabel(os.Getenv(prefix + "_LABEL")), nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
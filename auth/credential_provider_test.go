@@ -0,0 +1,213 @@
+package auth_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("Credential Providers", func() {
+	var handler http.Handler
+
+	BeforeEach(func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+	})
+
+	statusFor := func(wrapper *auth.Wrapper, username, password string) int {
+		request, err := http.NewRequest("GET", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.SetBasicAuth(username, password)
+		recorder := httptest.NewRecorder()
+		wrapper.Wrap(handler).ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	Describe("static credentials", func() {
+		It("does not accept expired credentials", func() {
+			wrapper := auth.NewWrapperWithProvider(auth.StaticCredentials{
+				auth.NewCredential("old", "password").WithExpiry(time.Now().Add(-time.Minute)),
+				auth.NewCredential("new", "password").WithExpiry(time.Now().Add(time.Hour)),
+			})
+
+			Expect(statusFor(wrapper, "old", "password")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "new", "password")).To(Equal(http.StatusCreated))
+		})
+	})
+
+	Describe("file credential provider", func() {
+		var path string
+
+		writeFile := func(contents string) {
+			GinkgoHelper()
+			Expect(os.WriteFile(path, []byte(contents), 0o600)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "credentials.json")
+			writeFile(`{"credentials":[{"username":"broker","password":"old-secret"}]}`)
+		})
+
+		It("accepts the credentials in the file", func() {
+			wrapper := auth.NewWrapperWithProvider(must(auth.NewFileCredentialProvider(path)))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "broker", "new-secret")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("supports overlapping credentials with an expiry", func() {
+			writeFile(`{"credentials":[
+				{"username":"broker","password":"old-secret","expires_at":"2000-01-01T00:00:00Z"},
+				{"username":"broker","password":"new-secret","expires_at":"2999-01-01T00:00:00Z"}
+			]}`)
+
+			wrapper := auth.NewWrapperWithProvider(must(auth.NewFileCredentialProvider(path)))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "broker", "new-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("picks up changes to the file", func() {
+			wrapper := auth.NewWrapperWithProvider(must(auth.NewFileCredentialProvider(path)))
+
+			writeFile(`{"credentials":[{"username":"broker","password":"new-secret"},{"username":"broker","password":"old-secret"}]}`)
+
+			Eventually(func() int {
+				return statusFor(wrapper, "broker", "new-secret")
+			}).WithTimeout(5 * time.Second).Should(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("reports credential labels", func() {
+			writeFile(`{"credentials":[{"username":"broker","password":"secret","label":"platform-a"}]}`)
+			wrapper := auth.NewWrapperWithProvider(must(auth.NewFileCredentialProvider(path)))
+
+			var principal *auth.Principal
+			request := must(http.NewRequest("GET", "", nil))
+			request.SetBasicAuth("broker", "secret")
+			wrapper.WrapFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = auth.RetrievePrincipalFromContext(r.Context())
+			}).ServeHTTP(httptest.NewRecorder(), request)
+
+			Expect(principal.Label).To(Equal("platform-a"))
+		})
+
+		It("reloads on demand", func() {
+			provider := must(auth.NewFileCredentialProvider(path))
+			wrapper := auth.NewWrapperWithProvider(provider)
+
+			writeFile(`{"credentials":[{"username":"broker","password":"new-secret"}]}`)
+			Expect(provider.Reload()).To(Succeed())
+
+			Expect(statusFor(wrapper, "broker", "new-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("reloads on SIGHUP", func() {
+			provider := must(auth.NewFileCredentialProvider(path))
+			wrapper := auth.NewWrapperWithProvider(provider)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			provider.ReloadOnSignal(ctx)
+
+			writeFile(`{"credentials":[{"username":"broker","password":"new-secret"}]}`)
+			process := must(os.FindProcess(os.Getpid()))
+			Expect(process.Signal(syscall.SIGHUP)).To(Succeed())
+
+			Eventually(func() int {
+				return statusFor(wrapper, "broker", "new-secret")
+			}).Should(Equal(http.StatusCreated))
+		})
+
+		It("keeps the previous credentials when the file becomes invalid", func() {
+			provider := must(auth.NewFileCredentialProvider(path))
+			wrapper := auth.NewWrapperWithProvider(provider)
+
+			writeFile(`not json`)
+			Expect(provider.Reload()).To(MatchError(ContainSubstring("parsing credentials file")))
+
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("logs errors reloading the file when it changes", func() {
+			logs := gbytes.NewBuffer()
+			provider := must(auth.NewFileCredentialProvider(path)).WithLogger(slog.New(slog.NewJSONHandler(logs, nil)))
+			wrapper := auth.NewWrapperWithProvider(provider)
+
+			writeFile(`not json at all`)
+			Eventually(func() *gbytes.Buffer {
+				statusFor(wrapper, "broker", "old-secret")
+				return logs
+			}).WithTimeout(5 * time.Second).Should(gbytes.Say(`"msg":"auth.reload-failed".*parsing credentials file`))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("fails when the file does not exist", func() {
+			_, err := auth.NewFileCredentialProvider(filepath.Join(GinkgoT().TempDir(), "missing.json"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails when a credential is incomplete", func() {
+			writeFile(`{"credentials":[{"username":"broker"}]}`)
+			_, err := auth.NewFileCredentialProvider(path)
+			Expect(err).To(MatchError(ContainSubstring("username and password must be specified")))
+		})
+	})
+
+	Describe("environment credential provider", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("BROKER_USERNAME", "broker")
+			GinkgoT().Setenv("BROKER_PASSWORD", "current-secret")
+			GinkgoT().Setenv("BROKER_1_USERNAME", "broker")
+			GinkgoT().Setenv("BROKER_1_PASSWORD", "old-secret")
+			GinkgoT().Setenv("BROKER_1_EXPIRES_AT", time.Now().Add(time.Hour).Format(time.RFC3339))
+		})
+
+		It("accepts the credentials from the environment", func() {
+			wrapper := auth.NewWrapperWithProvider(auth.NewEnvCredentialProvider("BROKER"))
+			Expect(statusFor(wrapper, "broker", "current-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "broker", "other-secret")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("does not accept expired credentials", func() {
+			GinkgoT().Setenv("BROKER_1_EXPIRES_AT", time.Now().Add(-time.Hour).Format(time.RFC3339))
+			wrapper := auth.NewWrapperWithProvider(auth.NewEnvCredentialProvider("BROKER"))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("ignores credentials with an invalid expiry", func() {
+			GinkgoT().Setenv("BROKER_1_EXPIRES_AT", "tomorrow")
+			wrapper := auth.NewWrapperWithProvider(auth.NewEnvCredentialProvider("BROKER"))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "broker", "current-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("ignores credentials with both a password and a password hash", func() {
+			GinkgoT().Setenv("BROKER_1_PASSWORD_HASH", string(must(bcrypt.GenerateFromPassword([]byte("hashed-secret"), bcrypt.MinCost))))
+			wrapper := auth.NewWrapperWithProvider(auth.NewEnvCredentialProvider("BROKER"))
+			Expect(statusFor(wrapper, "broker", "old-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "broker", "hashed-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "broker", "current-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("reads the environment on every request", func() {
+			wrapper := auth.NewWrapperWithProvider(auth.NewEnvCredentialProvider("BROKER"))
+			GinkgoT().Setenv("BROKER_PASSWORD", "rotated-secret")
+			Expect(statusFor(wrapper, "broker", "rotated-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "broker", "current-secret")).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -0,0 +1,15 @@
+package auth
+
+import "sync/atomic"
+
+// CountHashVerifications counts the password hashes that are verified. The returned count function
+// reports the number so far, and restore stops counting.
+func CountHashVerifications() (count func() int, restore func()) {
+	var n atomic.Int32
+	original := verifyPassword
+	verifyPassword = func(h passwordHash, password string) bool {
+		n.Add(1)
+		return original(h, password)
+	}
+	return func() int { return int(n.Load()) }, func() { verifyPassword = original }
+}
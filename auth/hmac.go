@@ -0,0 +1,385 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	hmacAlgorithm           = "HMAC-SHA256"
+	defaultHMACMaxSkew      = 5 * time.Minute
+	defaultHMACMaxBodyBytes = 4 << 20
+)
+
+// DefaultSignedHeaders are the headers signed by an HMACTransport when none are specified
+var DefaultSignedHeaders = []string{"Content-Type", "X-Broker-API-Version"}
+
+// HMACConfig configures an HMACWrapper. Keys maps key IDs to shared secrets, and is required.
+// RequiredHeaders lists headers that every signature must cover, in addition to the method,
+// path, query, body digest, timestamp and nonce which are always signed. MaxSkew is how far
+// the signature timestamp may be from the current time (default 5m). Nonces records the
+// nonces that have been used, and defaults to an in-memory cache. MaxBodyBytes limits the
+// size of the body that is read to check the signature (default 4 MiB); requests with larger
+// bodies are rejected with 413 Request Entity Too Large.
+type HMACConfig struct {
+	Keys            map[string][]byte
+	RequiredHeaders []string
+	MaxSkew         time.Duration
+	Nonces          NonceCache
+	MaxBodyBytes    int64
+}
+
+// NonceCache remembers nonces so that a signed request cannot be replayed. Seen records the nonce
+// until the expiry time, and reports whether it had already been recorded. An implementation backed
+// by shared storage is needed if several broker instances are behind a load balancer.
+type NonceCache interface {
+	Seen(nonce string, expiry time.Time) bool
+}
+
+// HMACWrapper authenticates requests signed with a shared secret using an Authorization header of the form:
+//
+//	HMAC-SHA256 keyId="<id>",timestamp="<unix seconds>",nonce="<random>",headers="<name;name>",signature="<base64>"
+//
+// The signature is the HMAC-SHA256 of the lines:
+//
+//	<method>
+//	<path>[?<query>]
+//	<lowercase header name>:<trimmed value>   (one line per signed header, in the listed order)
+//	<hex SHA-256 of the body>
+//	<timestamp>
+//	<nonce>
+//
+// Requests are rejected if the timestamp is outside the allowed clock skew, or if the nonce has been
+// used before. See HMACTransport and SignRequest() for signing requests.
+type HMACWrapper struct {
+	config HMACConfig
+}
+
+func NewHMACWrapper(config HMACConfig) *HMACWrapper {
+	if config.MaxSkew <= 0 {
+		config.MaxSkew = defaultHMACMaxSkew
+	}
+	if config.Nonces == nil {
+		config.Nonces = NewMemoryNonceCache()
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultHMACMaxBodyBytes
+	}
+	return &HMACWrapper{config: config}
+}
+
+func (wrapper *HMACWrapper) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			respondHMACFailure(w, err)
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	})
+}
+
+func (wrapper *HMACWrapper) WrapFunc(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := wrapper.Authenticate(r)
+		if err != nil {
+			respondHMACFailure(w, err)
+			return
+		}
+
+		handlerFunc(w, r.WithContext(AddPrincipalToContext(r.Context(), principal)))
+	}
+}
+
+func respondHMACFailure(w http.ResponseWriter, err error) {
+	if respondBodyTooLarge(w, err) {
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", hmacAlgorithm)
+	http.Error(w, notAuthorized, http.StatusUnauthorized)
+}
+
+// respondBodyTooLarge responds with 413 Request Entity Too Large and returns true if the
+// request was rejected because its body is larger than the limit
+func respondBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+
+	http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	return true
+}
+
+func (wrapper *HMACWrapper) Challenge() string {
+	return hmacAlgorithm
+}
+
+// Authenticate verifies the HMAC signature of the request
+func (wrapper *HMACWrapper) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, params, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || scheme != hmacAlgorithm {
+		return nil, errors.New("no HMAC signature")
+	}
+
+	sig, err := parseHMACParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, ok := wrapper.config.Keys[sig.keyID]
+	if !ok {
+		return nil, errors.New("unknown key ID")
+	}
+
+	for _, h := range wrapper.config.RequiredHeaders {
+		if !slices.Contains(sig.headers, strings.ToLower(h)) {
+			return nil, fmt.Errorf("header %q must be signed", h)
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(sig.timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid timestamp")
+	}
+	signedAt := time.Unix(timestamp, 0)
+	if skew := time.Since(signedAt).Abs(); skew > wrapper.config.MaxSkew {
+		return nil, errors.New("timestamp outside the allowed clock skew")
+	}
+
+	body, err := readBody(r, wrapper.config.MaxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := signature(secret, canonicalRequest(r, sig.headers, body, sig.timestamp, sig.nonce))
+	if !hmac.Equal(expected, sig.signature) {
+		return nil, errors.New("signature mismatch")
+	}
+
+	// The nonce is only recorded once the signature is verified, so that unauthenticated
+	// clients cannot fill the cache or block nonces
+	if wrapper.config.Nonces.Seen(sig.keyID+":"+sig.nonce, signedAt.Add(wrapper.config.MaxSkew)) {
+		return nil, errors.New("nonce has already been used")
+	}
+
+	return &Principal{Name: sig.keyID, Scheme: SchemeHMAC}, nil
+}
+
+type hmacSignature struct {
+	keyID     string
+	timestamp string
+	nonce     string
+	headers   []string
+	signature []byte
+}
+
+func parseHMACParams(params string) (hmacSignature, error) {
+	var sig hmacSignature
+	for _, param := range strings.Split(params, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			return hmacSignature{}, errors.New("malformed HMAC parameter")
+		}
+		value = strings.Trim(value, `"`)
+
+		switch name {
+		case "keyId":
+			sig.keyID = value
+		case "timestamp":
+			sig.timestamp = value
+		case "nonce":
+			sig.nonce = value
+		case "headers":
+			if value != "" {
+				sig.headers = strings.Split(strings.ToLower(value), ";")
+			}
+		case "signature":
+			var err error
+			if sig.signature, err = base64.StdEncoding.DecodeString(value); err != nil {
+				return hmacSignature{}, errors.New("malformed HMAC signature")
+			}
+		}
+	}
+
+	if sig.keyID == "" || sig.timestamp == "" || sig.nonce == "" || len(sig.signature) == 0 {
+		return hmacSignature{}, errors.New("keyId, timestamp, nonce and signature are required")
+	}
+
+	return sig, nil
+}
+
+// SignRequest adds an HMAC-SHA256 Authorization header to the request, signing the specified headers
+// in addition to the method, path, query and body. See HMACWrapper for the format.
+func SignRequest(r *http.Request, keyID string, secret []byte, signedHeaders ...string) error {
+	body, err := readBody(r, 0)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	headers := make([]string, 0, len(signedHeaders))
+	for _, h := range signedHeaders {
+		headers = append(headers, strings.ToLower(h))
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	n := hex.EncodeToString(nonce)
+	sig := signature(secret, canonicalRequest(r, headers, body, timestamp, n))
+
+	r.Header.Set("Authorization", fmt.Sprintf(`%s keyId="%s",timestamp="%s",nonce="%s",headers="%s",signature="%s"`,
+		hmacAlgorithm, keyID, timestamp, n, strings.Join(headers, ";"), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+func canonicalRequest(r *http.Request, headers []string, body []byte, timestamp, nonce string) []byte {
+	var b strings.Builder
+	b.WriteString(r.Method + "\n")
+	if path := r.URL.EscapedPath(); path != "" {
+		b.WriteString(path)
+	} else {
+		// An empty path is sent as "/"
+		b.WriteString("/")
+	}
+	if r.URL.RawQuery != "" {
+		b.WriteString("?" + r.URL.RawQuery)
+	}
+	b.WriteString("\n")
+	for _, h := range headers {
+		b.WriteString(h + ":" + strings.TrimSpace(strings.Join(r.Header.Values(h), ",")) + "\n")
+	}
+	digest := sha256.Sum256(body)
+	b.WriteString(hex.EncodeToString(digest[:]) + "\n")
+	b.WriteString(timestamp + "\n")
+	b.WriteString(nonce)
+	return []byte(b.String())
+}
+
+func signature(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// readBody reads the request body, and replaces it so that it can be read again. If limit is
+// positive, an *http.MaxBytesError is returned for a body that is larger.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := readAll(r.Body, limit)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func readAll(body io.ReadCloser, limit int64) ([]byte, error) {
+	if limit > 0 {
+		// There is no ResponseWriter, so the server is not told to close the connection
+		body = http.MaxBytesReader(nil, body, limit)
+	}
+	return io.ReadAll(body)
+}
+
+// HMACTransport is an http.RoundTripper that signs requests for an HMACWrapper. SignedHeaders
+// defaults to DefaultSignedHeaders, and Base defaults to http.DefaultTransport. Requests with
+// bodies larger than MaxBodyBytes (default 4 MiB, the default limit of HMACWrapper) are not sent.
+type HMACTransport struct {
+	KeyID         string
+	Secret        []byte
+	SignedHeaders []string
+	Base          http.RoundTripper
+	MaxBodyBytes  int64
+}
+
+func (t *HMACTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request, so the clone is signed
+	signed := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		maxBodyBytes := t.MaxBodyBytes
+		if maxBodyBytes <= 0 {
+			maxBodyBytes = defaultHMACMaxBodyBytes
+		}
+		body, err := readAll(req.Body, maxBodyBytes)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	signedHeaders := t.SignedHeaders
+	if signedHeaders == nil {
+		signedHeaders = DefaultSignedHeaders
+	}
+
+	if err := SignRequest(signed, t.KeyID, t.Secret, signedHeaders...); err != nil {
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
+
+// MemoryNonceCache is an in-memory NonceCache
+type MemoryNonceCache struct {
+	lock      sync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+}
+
+func NewMemoryNonceCache() *MemoryNonceCache {
+	return &MemoryNonceCache{nonces: make(map[string]time.Time)}
+}
+
+func (c *MemoryNonceCache) Seen(nonce string, expiry time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastPrune) > time.Minute {
+		for n, e := range c.nonces {
+			if now.After(e) {
+				delete(c.nonces, n)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if e, ok := c.nonces[nonce]; ok && !now.After(e) {
+		return true
+	}
+
+	c.nonces[nonce] = expiry
+	return false
+}
@@ -0,0 +1,175 @@
+package auth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("HMAC Wrapper", func() {
+	var (
+		secret    []byte
+		config    auth.HMACConfig
+		server    *httptest.Server
+		principal *auth.Principal
+		body      string
+	)
+
+	client := func(transport *auth.HMACTransport) *http.Client {
+		return &http.Client{Transport: transport}
+	}
+
+	BeforeEach(func() {
+		secret = []byte("shared-secret")
+		config = auth.HMACConfig{Keys: map[string][]byte{"automation": secret}}
+		principal = nil
+		body = ""
+	})
+
+	JustBeforeEach(func() {
+		handler := auth.NewHMACWrapper(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = auth.RetrievePrincipalFromContext(r.Context())
+			body = string(must(io.ReadAll(r.Body)))
+			w.WriteHeader(http.StatusCreated)
+		}))
+		server = httptest.NewServer(handler)
+		DeferCleanup(server.Close)
+	})
+
+	It("accepts requests signed by an HMACTransport", func() {
+		request := must(http.NewRequest("PUT", server.URL+"/v2/service_instances/1?accepts_incomplete=true", strings.NewReader(`{"service_id":"s"}`)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-Broker-API-Version", "2.17")
+
+		response := must(client(&auth.HMACTransport{KeyID: "automation", Secret: secret}).Do(request))
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+		Expect(body).To(Equal(`{"service_id":"s"}`))
+		Expect(principal).To(Equal(&auth.Principal{Name: "automation", Scheme: auth.SchemeHMAC}))
+		Expect(request.Header.Get("Authorization")).To(BeEmpty(), "the original request should not be modified")
+	})
+
+	It("rejects unsigned requests with a challenge", func() {
+		response := must(http.Get(server.URL))
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+		Expect(response.Header.Get("WWW-Authenticate")).To(Equal("HMAC-SHA256"))
+	})
+
+	It("rejects requests signed with an unknown key", func() {
+		response := must(client(&auth.HMACTransport{KeyID: "other", Secret: secret}).Get(server.URL))
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+
+	It("rejects requests signed with the wrong secret", func() {
+		response := must(client(&auth.HMACTransport{KeyID: "automation", Secret: []byte("wrong")}).Get(server.URL))
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+
+	It("rejects requests that are modified after signing", func() {
+		request := must(http.NewRequest("PUT", server.URL+"/v2/service_instances/1", strings.NewReader(`{"plan_id":"small"}`)))
+		Expect(auth.SignRequest(request, "automation", secret)).To(Succeed())
+		request.Body = io.NopCloser(strings.NewReader(`{"plan_id":"large"}`))
+
+		response := must(http.DefaultClient.Do(request))
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+
+	It("rejects a signed header that is changed", func() {
+		request := must(http.NewRequest("GET", server.URL, nil))
+		request.Header.Set("X-Broker-API-Version", "2.17")
+		Expect(auth.SignRequest(request, "automation", secret, "X-Broker-API-Version")).To(Succeed())
+		request.Header.Set("X-Broker-API-Version", "2.14")
+
+		response := must(http.DefaultClient.Do(request))
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+
+	It("rejects replayed requests", func() {
+		request := must(http.NewRequest("GET", server.URL, nil))
+		Expect(auth.SignRequest(request, "automation", secret)).To(Succeed())
+
+		Expect(must(http.DefaultClient.Do(request))).To(HaveHTTPStatus(http.StatusCreated))
+		Expect(must(http.DefaultClient.Do(request))).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+
+	It("rejects timestamps outside the clock skew window", func() {
+		sign := func(timestamp time.Time) *http.Request {
+			ts := fmt.Sprint(timestamp.Unix())
+			digest := sha256.Sum256(nil)
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte("GET\n/\n" + hex.EncodeToString(digest[:]) + "\n" + ts + "\n" + "nonce-" + ts))
+
+			request := must(http.NewRequest("GET", server.URL+"/", nil))
+			request.Header.Set("Authorization", fmt.Sprintf(`HMAC-SHA256 keyId="automation",timestamp="%s",nonce="nonce-%s",headers="",signature="%s"`,
+				ts, ts, base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+			return request
+		}
+
+		Expect(must(http.DefaultClient.Do(sign(time.Now().Add(-time.Minute))))).To(HaveHTTPStatus(http.StatusCreated))
+		Expect(must(http.DefaultClient.Do(sign(time.Now().Add(-10 * time.Minute))))).To(HaveHTTPStatus(http.StatusUnauthorized))
+		Expect(must(http.DefaultClient.Do(sign(time.Now().Add(10 * time.Minute))))).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+
+	When("headers are required", func() {
+		BeforeEach(func() {
+			config.RequiredHeaders = []string{"X-Broker-API-Version"}
+		})
+
+		It("rejects signatures that do not cover them", func() {
+			transport := &auth.HMACTransport{KeyID: "automation", Secret: secret, SignedHeaders: []string{"Content-Type"}}
+			Expect(must(client(transport).Get(server.URL))).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+			transport.SignedHeaders = nil
+			Expect(must(client(transport).Get(server.URL))).To(HaveHTTPStatus(http.StatusCreated))
+		})
+	})
+
+	When("the body is larger than the limit", func() {
+		BeforeEach(func() {
+			config.MaxBodyBytes = 16
+		})
+
+		It("rejects the request as too large", func() {
+			request := must(http.NewRequest("PUT", server.URL, strings.NewReader(`{"service_id":"a-service"}`)))
+			Expect(auth.SignRequest(request, "automation", secret)).To(Succeed())
+
+			response := must(http.DefaultClient.Do(request))
+			Expect(response).To(HaveHTTPStatus(http.StatusRequestEntityTooLarge))
+			Expect(body).To(BeEmpty())
+		})
+
+		It("accepts bodies within the limit", func() {
+			request := must(http.NewRequest("PUT", server.URL, strings.NewReader(`{"plan_id":"p"}`)))
+			Expect(auth.SignRequest(request, "automation", secret)).To(Succeed())
+
+			Expect(must(http.DefaultClient.Do(request))).To(HaveHTTPStatus(http.StatusCreated))
+		})
+	})
+
+	It("does not send requests with bodies larger than the limit of the transport", func() {
+		transport := &auth.HMACTransport{KeyID: "automation", Secret: secret, MaxBodyBytes: 4}
+		_, err := client(transport).Post(server.URL, "application/json", strings.NewReader(`{"plan_id":"p"}`))
+		Expect(err).To(MatchError(ContainSubstring("request body too large")))
+	})
+
+	Describe("MemoryNonceCache", func() {
+		It("reports nonces that have been seen until they expire", func() {
+			cache := auth.NewMemoryNonceCache()
+			Expect(cache.Seen("a", time.Now().Add(time.Hour))).To(BeFalse())
+			Expect(cache.Seen("a", time.Now().Add(time.Hour))).To(BeTrue())
+			Expect(cache.Seen("b", time.Now().Add(-time.Second))).To(BeFalse())
+			Expect(cache.Seen("b", time.Now().Add(time.Hour))).To(BeFalse())
+		})
+	})
+})
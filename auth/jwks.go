@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// KeySet holds the keys that a BearerWrapper will accept token signatures from.
+// Keys are identified by their key ID ("kid"), which may be empty.
+type KeySet struct {
+	keys map[string]any
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]any)}
+}
+
+// Add adds a verification key to the key set. The key must be an *rsa.PublicKey (RS256),
+// an *ecdsa.PublicKey on the P-256 curve (ES256), or a []byte shared secret (HS256).
+func (k *KeySet) Add(kid string, key any) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return fmt.Errorf("key %q: only the P-256 curve is supported", kid)
+		}
+	case []byte:
+		if len(key) == 0 {
+			return fmt.Errorf("key %q: empty shared secret", kid)
+		}
+	default:
+		return fmt.Errorf("key %q: unsupported key type %T", kid, key)
+	}
+
+	k.keys[kid] = key
+	return nil
+}
+
+// LoadJWKSFile reads a JSON Web Key Set (RFC 7517) from a local file
+func LoadJWKSFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses a JSON Web Key Set (RFC 7517). RSA, EC (P-256) and symmetric ("oct") keys
+// are supported. Keys that are marked for a use other than signing are ignored.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+
+	ks := NewKeySet()
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.key()
+		if err != nil {
+			return nil, fmt.Errorf("parsing JWKS key %d: %w", i, err)
+		}
+		if err := ks.Add(jwk.Kid, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, errors.New("parsing JWKS: no signing keys found")
+	}
+
+	return ks, nil
+}
+
+func (k *KeySet) candidates(kid string) []any {
+	if k == nil {
+		return nil
+	}
+
+	if kid != "" {
+		if key, ok := k.keys[kid]; ok {
+			return []any{key}
+		}
+		return nil
+	}
+
+	result := make([]any, 0, len(k.keys))
+	for _, key := range k.keys {
+		result = append(result, key)
+	}
+	return result
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func (j jsonWebKey) key() (any, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		if x.BitLen() > 256 || y.BitLen() > 256 {
+			return nil, errors.New("point is not on the P-256 curve")
+		}
+		if _, err := ecdh.P256().NewPublicKey(uncompressedPoint(x, y)); err != nil {
+			return nil, errors.New("point is not on the P-256 curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(j.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func uncompressedPoint(x, y *big.Int) []byte {
+	point := make([]byte, 65)
+	point[0] = 4
+	x.FillBytes(point[1:33])
+	y.FillBytes(point[33:])
+	return point
+}
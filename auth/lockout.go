@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"container/list"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBaseDelay   = time.Second
+	defaultMaxDelay    = 15 * time.Minute
+	defaultResetAfter  = time.Hour
+	defaultMaxTracked  = 10000
+)
+
+// LockoutConfig configures a Lockout. Zero values are replaced with defaults.
+type LockoutConfig struct {
+	// MaxAttempts is the number of consecutive failures allowed before a client is locked out (default 5)
+	MaxAttempts int
+	// BaseDelay is the duration of the first lockout, which doubles with every further failure (default 1s)
+	BaseDelay time.Duration
+	// MaxDelay is the longest lockout duration (default 15m)
+	MaxDelay time.Duration
+	// ResetAfter is how long failures are remembered for after the most recent one (default 1h)
+	ResetAfter time.Duration
+	// MaxTracked is the number of source IPs and usernames whose failures are remembered (default 10000).
+	// When it is reached, the failures that are not locked out and are the oldest are forgotten first.
+	// It also limits the number of source IPs that are remembered as having authenticated.
+	MaxTracked int
+	// ClientIP returns the source IP of a request. The default uses the remote address of the connection,
+	// which should be replaced when the broker is behind a proxy or load balancer.
+	ClientIP func(*http.Request) string
+	// Logger receives an event for each failure and lockout (default slog.Default())
+	Logger *slog.Logger
+}
+
+// Lockout tracks failed authentication attempts per source IP and per username. Once either has
+// failed MaxAttempts times in a row it is locked out for an exponentially increasing duration,
+// during which requests are rejected with 429 Too Many Requests without checking the credentials.
+// A successful authentication resets the count for the source IP and username. Only requests
+// carrying basic authentication credentials are counted.
+//
+// So that failures from elsewhere cannot lock out a legitimate client, a source IP that has
+// authenticated as a username within ResetAfter is not affected by the failures for the username,
+// and its own failures and successes only count for the source IP. Attempts that are being checked count
+// towards MaxAttempts, so that concurrent requests cannot make more attempts than allowed.
+type Lockout struct {
+	config LockoutConfig
+
+	lock     sync.Mutex
+	attempts map[string]*failedAttempts
+	// unlocked and locked order the tracked keys from the most to the least recent failure, so that the
+	// oldest can be forgotten without a scan. Keys that have been locked out are forgotten last.
+	unlocked list.List
+	locked   list.List
+	// known holds the source IPs that have recently authenticated as each username, most recent first
+	known      map[string]*list.Element
+	knownOrder list.List
+}
+
+type failedAttempts struct {
+	key         string
+	count       int
+	pending     int
+	last        time.Time
+	lockedUntil time.Time
+	element     *list.Element
+	list        *list.List
+}
+
+type knownClient struct {
+	key  string
+	last time.Time
+}
+
+func NewLockout(config LockoutConfig) *Lockout {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = defaultBaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = defaultMaxDelay
+	}
+	if config.ResetAfter <= 0 {
+		config.ResetAfter = defaultResetAfter
+	}
+	if config.MaxTracked <= 0 {
+		config.MaxTracked = defaultMaxTracked
+	}
+	if config.ClientIP == nil {
+		config.ClientIP = remoteIP
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &Lockout{config: config, attempts: make(map[string]*failedAttempts), known: make(map[string]*list.Element)}
+}
+
+// WithLockout enables brute-force protection on the Wrapper
+func (wrapper *Wrapper) WithLockout(lockout *Lockout) *Wrapper {
+	wrapper.lockout = lockout
+	return wrapper
+}
+
+// lockedOutError is returned when the source IP or username of a request is locked out
+type lockedOutError struct {
+	retryAfter time.Duration
+}
+
+func (e *lockedOutError) Error() string {
+	return "too many failed authentication attempts"
+}
+
+func (e *lockedOutError) respond(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds()))))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// attempt is an authentication attempt allowed by check. It is counted until failure or success is called.
+type attempt struct {
+	lockout  *Lockout
+	ip       string
+	username string
+	keys     []string
+}
+
+// check returns a lockedOutError if the request should be rejected, and otherwise reserves an attempt
+// for the source IP and username
+func (l *Lockout) check(r *http.Request) (*attempt, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	ip := l.config.ClientIP(r)
+	username, _, _ := r.BasicAuth()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.forget(now)
+
+	keys := []string{"ip:" + ip}
+	if username != "" && !l.isKnown(username, ip, now) {
+		keys = append(keys, "username:"+username)
+	}
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		a, ok := l.attempts[key]
+		switch {
+		case !ok:
+		case now.Before(a.lockedUntil):
+			retryAfter = max(retryAfter, a.lockedUntil.Sub(now))
+		case a.pending >= max(l.config.MaxAttempts-a.failures(now, l.config.ResetAfter), 1):
+			// the attempts being checked could lock the key out
+			retryAfter = max(retryAfter, l.config.BaseDelay)
+		}
+	}
+	if retryAfter > 0 {
+		return nil, &lockedOutError{retryAfter: retryAfter}
+	}
+
+	for _, key := range keys {
+		a, ok := l.attempts[key]
+		if !ok {
+			a = l.track(key, now)
+		}
+		a.pending++
+	}
+	return &attempt{lockout: l, ip: ip, username: username, keys: keys}, nil
+}
+
+// failures is the number of consecutive failures that are remembered at the time
+func (a *failedAttempts) failures(now time.Time, resetAfter time.Duration) int {
+	if now.Sub(a.last) > resetAfter {
+		return 0
+	}
+	return a.count
+}
+
+func (a *attempt) failure() {
+	if a == nil {
+		return
+	}
+	l := a.lockout
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	var failures int
+	var lockedOut []*failedAttempts
+	for _, key := range a.keys {
+		f, ok := l.attempts[key]
+		if !ok {
+			f = l.track(key, now)
+		} else if f.pending > 0 {
+			f.pending--
+		}
+		f.count = f.failures(now, l.config.ResetAfter) + 1
+		f.last = now
+		failures = max(failures, f.count)
+
+		if f.count >= l.config.MaxAttempts {
+			f.lockedUntil = now.Add(l.delay(f.count - l.config.MaxAttempts))
+			lockedOut = append(lockedOut, f)
+			l.moveToFront(f, &l.locked)
+		} else {
+			l.moveToFront(f, &l.unlocked)
+		}
+	}
+
+	l.config.Logger.Warn("auth.failed", slog.String("ip", a.ip), slog.String("username", a.username), slog.Int("failures", failures))
+	for _, f := range lockedOut {
+		l.config.Logger.Warn("auth.locked-out",
+			slog.String("key", f.key), slog.String("ip", a.ip), slog.String("username", a.username),
+			slog.Int("failures", f.count), slog.Duration("duration", f.lockedUntil.Sub(now)))
+	}
+}
+
+func (a *attempt) success() {
+	if a == nil {
+		return
+	}
+	l := a.lockout
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, key := range a.keys {
+		l.remove(key)
+	}
+	if a.username != "" {
+		l.remember(a.username, a.ip, time.Now())
+	}
+}
+
+// delay is the lockout duration after the specified number of failures beyond MaxAttempts
+func (l *Lockout) delay(extraFailures int) time.Duration {
+	delay := l.config.BaseDelay
+	for i := 0; i < extraFailures && delay < l.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, l.config.MaxDelay)
+}
+
+// track starts counting the failures for a key. If MaxTracked keys are already tracked, the least recent
+// failures are forgotten, preferring those that are not locked out, so that clients sending many different
+// usernames cannot grow the map without bound or make the lockout of their source IP be forgotten.
+func (l *Lockout) track(key string, now time.Time) *failedAttempts {
+	for len(l.attempts) >= l.config.MaxTracked {
+		oldest := l.unlocked.Back()
+		if oldest == nil {
+			oldest = l.locked.Back()
+		}
+		l.remove(oldest.Value.(*failedAttempts).key)
+	}
+
+	a := &failedAttempts{key: key, last: now}
+	l.attempts[key] = a
+	l.moveToFront(a, &l.unlocked)
+	return a
+}
+
+func (l *Lockout) moveToFront(a *failedAttempts, to *list.List) {
+	if a.list == to {
+		to.MoveToFront(a.element)
+		return
+	}
+	if a.list != nil {
+		a.list.Remove(a.element)
+	}
+	a.element = to.PushFront(a)
+	a.list = to
+}
+
+func (l *Lockout) remove(key string) {
+	if a, ok := l.attempts[key]; ok {
+		a.list.Remove(a.element)
+		delete(l.attempts, key)
+	}
+}
+
+// forget removes the failures and known source IPs that are older than ResetAfter, starting with the least recent
+func (l *Lockout) forget(now time.Time) {
+	for _, attempts := range []*list.List{&l.unlocked, &l.locked} {
+		for e := attempts.Back(); e != nil; e = attempts.Back() {
+			a := e.Value.(*failedAttempts)
+			if now.Sub(a.last) <= l.config.ResetAfter || now.Before(a.lockedUntil) || a.pending > 0 {
+				break
+			}
+			l.remove(a.key)
+		}
+	}
+
+	for e := l.knownOrder.Back(); e != nil && now.Sub(e.Value.(*knownClient).last) > l.config.ResetAfter; e = l.knownOrder.Back() {
+		delete(l.known, e.Value.(*knownClient).key)
+		l.knownOrder.Remove(e)
+	}
+}
+
+func (l *Lockout) isKnown(username, ip string, now time.Time) bool {
+	e, ok := l.known[knownKey(username, ip)]
+	return ok && now.Sub(e.Value.(*knownClient).last) <= l.config.ResetAfter
+}
+
+// remember records that the source IP has authenticated as the username, forgetting the least recent
+// source IPs when there are more than MaxTracked
+func (l *Lockout) remember(username, ip string, now time.Time) {
+	key := knownKey(username, ip)
+	if e, ok := l.known[key]; ok {
+		e.Value.(*knownClient).last = now
+		l.knownOrder.MoveToFront(e)
+		return
+	}
+
+	l.known[key] = l.knownOrder.PushFront(&knownClient{key: key, last: now})
+	for len(l.known) > l.config.MaxTracked {
+		e := l.knownOrder.Back()
+		delete(l.known, e.Value.(*knownClient).key)
+		l.knownOrder.Remove(e)
+	}
+}
+
+func knownKey(username, ip string) string {
+	return ip + "\x00" + username
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,212 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("Lockout", func() {
+	var (
+		logBuffer *gbytes.Buffer
+		config    auth.LockoutConfig
+		handler   http.Handler
+	)
+
+	attempt := func(ip, username, password string) *httptest.ResponseRecorder {
+		request := must(http.NewRequest("GET", "", nil))
+		request.RemoteAddr = ip + ":12345"
+		request.SetBasicAuth(username, password)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	logEvents := func() (events []map[string]any) {
+		for _, line := range strings.Split(strings.TrimSpace(string(logBuffer.Contents())), "\n") {
+			var event map[string]any
+			Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+			events = append(events, event)
+		}
+		return events
+	}
+
+	BeforeEach(func() {
+		logBuffer = gbytes.NewBuffer()
+		config = auth.LockoutConfig{
+			MaxAttempts: 3,
+			BaseDelay:   100 * time.Millisecond,
+			Logger:      slog.New(slog.NewJSONHandler(logBuffer, nil)),
+		}
+	})
+
+	JustBeforeEach(func() {
+		wrapper := auth.NewWrapper("broker", "secret").WithLockout(auth.NewLockout(config))
+		handler = wrapper.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+	})
+
+	It("locks out a source IP after too many failures", func() {
+		for range 3 {
+			Expect(attempt("10.0.0.1", "guess", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		}
+
+		response := attempt("10.0.0.1", "broker", "secret")
+		Expect(response.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(response.Header().Get("Retry-After")).To(Equal("1"))
+
+		Expect(attempt("10.0.0.2", "broker", "secret").Code).To(Equal(http.StatusCreated))
+	})
+
+	It("locks out a username regardless of the source IP", func() {
+		Expect(attempt("10.0.0.1", "broker", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		Expect(attempt("10.0.0.2", "broker", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		Expect(attempt("10.0.0.3", "broker", "wrong").Code).To(Equal(http.StatusUnauthorized))
+
+		Expect(attempt("10.0.0.4", "broker", "secret").Code).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("does not lock out a source IP that has authenticated as the username", func() {
+		Expect(attempt("10.0.0.9", "broker", "secret").Code).To(Equal(http.StatusCreated))
+
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			Expect(attempt(ip, "broker", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		}
+		Expect(attempt("10.0.0.4", "broker", "secret").Code).To(Equal(http.StatusTooManyRequests))
+
+		Expect(attempt("10.0.0.9", "broker", "secret").Code).To(Equal(http.StatusCreated))
+		Expect(attempt("10.0.0.4", "broker", "secret").Code).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("counts attempts that are being checked, so that concurrent requests cannot make more attempts", func() {
+		release := make(chan struct{})
+		credentials := blockingCredentials{release: release, StaticCredentials: auth.StaticCredentials{auth.NewCredential("broker", "secret")}}
+		handler = auth.NewWrapperWithProvider(credentials).WithLockout(auth.NewLockout(config)).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		codes := make(chan int, 5)
+		for range 5 {
+			go func() {
+				defer GinkgoRecover()
+				codes <- attempt("10.0.0.1", "guess", "wrong").Code
+			}()
+		}
+
+		Eventually(codes).Should(Receive(Equal(http.StatusTooManyRequests)))
+		Eventually(codes).Should(Receive(Equal(http.StatusTooManyRequests)))
+		Consistently(codes).ShouldNot(Receive())
+
+		close(release)
+		for range 3 {
+			Eventually(codes).Should(Receive(Equal(http.StatusUnauthorized)))
+		}
+	})
+
+	It("doubles the lockout after each further failure", func() {
+		for range 3 {
+			attempt("10.0.0.1", "guess", "wrong")
+		}
+		Eventually(func() int { return attempt("10.0.0.1", "guess", "wrong").Code }).Should(Equal(http.StatusUnauthorized))
+
+		Expect(attempt("10.0.0.1", "broker", "secret").Code).To(Equal(http.StatusTooManyRequests))
+		Consistently(func() int { return attempt("10.0.0.1", "broker", "secret").Code }, 150*time.Millisecond).Should(Equal(http.StatusTooManyRequests))
+		Eventually(func() int { return attempt("10.0.0.1", "broker", "secret").Code }).Should(Equal(http.StatusCreated))
+	})
+
+	It("resets the failure count after a successful authentication", func() {
+		attempt("10.0.0.1", "broker", "wrong")
+		attempt("10.0.0.1", "broker", "wrong")
+		Expect(attempt("10.0.0.1", "broker", "secret").Code).To(Equal(http.StatusCreated))
+
+		attempt("10.0.0.1", "broker", "wrong")
+		attempt("10.0.0.1", "broker", "wrong")
+		Expect(attempt("10.0.0.1", "broker", "secret").Code).To(Equal(http.StatusCreated))
+	})
+
+	When("many usernames are tried", func() {
+		BeforeEach(func() {
+			config.MaxTracked = 4
+			config.BaseDelay = time.Minute
+		})
+
+		It("forgets the oldest failures that are not locked out", func() {
+			for range 3 {
+				attempt("10.0.0.1", "broker", "wrong")
+			}
+			attempt("10.0.0.2", "other", "wrong")
+			attempt("10.0.0.2", "other", "wrong")
+
+			for i := range 10 {
+				Expect(attempt(fmt.Sprintf("10.0.1.%d", i), fmt.Sprintf("random-%d", i), "wrong").Code).To(Equal(http.StatusUnauthorized))
+			}
+
+			Expect(attempt("10.0.0.3", "broker", "secret").Code).To(Equal(http.StatusTooManyRequests))
+			attempt("10.0.0.2", "other", "wrong")
+			Expect(attempt("10.0.0.2", "other", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	It("logs each failure and lockout", func() {
+		for range 3 {
+			attempt("10.0.0.1", "guess", "wrong")
+		}
+
+		events := logEvents()
+		Expect(events).To(HaveLen(5))
+		Expect(events[0]).To(And(
+			HaveKeyWithValue("msg", "auth.failed"),
+			HaveKeyWithValue("ip", "10.0.0.1"),
+			HaveKeyWithValue("username", "guess"),
+			HaveKeyWithValue("failures", BeNumerically("==", 1)),
+		))
+		Expect(events[2]).To(HaveKeyWithValue("failures", BeNumerically("==", 3)))
+		Expect(events[3]).To(And(
+			HaveKeyWithValue("msg", "auth.locked-out"),
+			HaveKeyWithValue("key", "ip:10.0.0.1"),
+		))
+		Expect(events[4]).To(HaveKeyWithValue("key", "username:guess"))
+	})
+
+	When("a ClientIP function is configured", func() {
+		BeforeEach(func() {
+			config.ClientIP = func(r *http.Request) string {
+				return r.Header.Get("X-Forwarded-For")
+			}
+		})
+
+		It("is used to identify the source IP", func() {
+			for _, username := range []string{"a", "b", "c"} {
+				request := must(http.NewRequest("GET", "", nil))
+				request.Header.Set("X-Forwarded-For", "192.0.2.1")
+				request.SetBasicAuth(username, "wrong")
+				handler.ServeHTTP(httptest.NewRecorder(), request)
+			}
+
+			Expect(attempt("10.0.0.1", "broker", "secret").Code).To(Equal(http.StatusCreated))
+			Expect(logEvents()[0]).To(HaveKeyWithValue("ip", "192.0.2.1"))
+		})
+	})
+})
+
+// blockingCredentials waits until it is released before returning the credentials
+type blockingCredentials struct {
+	release <-chan struct{}
+	auth.StaticCredentials
+}
+
+func (c blockingCredentials) Credentials() []auth.Credential {
+	<-c.release
+	return c.StaticCredentials
+}
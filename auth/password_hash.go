@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordHash verifies a plaintext password against a stored hash
+type passwordHash interface {
+	matches(password string) bool
+}
+
+// verifyPassword verifies a password against a hash. It is replaced in tests to count the verifications.
+var verifyPassword = func(h passwordHash, password string) bool {
+	return h.matches(password)
+}
+
+// verifiedHash remembers the last username and password that matched a hash, so that a platform which
+// sends the same credentials with every request does not wait for the slow hash each time
+type verifiedHash struct {
+	passwordHash
+	verified atomic.Pointer[[sha256.Size]byte]
+}
+
+func (h *verifiedHash) matches(username [sha256.Size]byte, password string) bool {
+	key := sha256.Sum256(append(username[:], password...))
+	if verified := h.verified.Load(); verified != nil && subtle.ConstantTimeCompare(verified[:], key[:]) == 1 {
+		return true
+	}
+
+	if !verifyPassword(h.passwordHash, password) {
+		return false
+	}
+	h.verified.Store(&key)
+	return true
+}
+
+// NewHashedCredential creates a Credential from a username and a password hash, so that the
+// plaintext password does not need to be stored. Supported formats are bcrypt ("$2a$", "$2b$", "$2y$")
+// and argon2 in PHC string format ("$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>", or "$argon2i$...").
+func NewHashedCredential(username, hash string) (Credential, error) {
+	h, err := parsePasswordHash(hash)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	u := sha256.Sum256([]byte(username))
+	return Credential{username: u[:], hash: &verifiedHash{passwordHash: h}}, nil
+}
+
+// NewWrapperFromHashes creates a Wrapper from a map of usernames to password hashes.
+// See NewHashedCredential() for the supported hash formats.
+func NewWrapperFromHashes(users map[string]string) (*Wrapper, error) {
+	var cs StaticCredentials
+	for username, hash := range users {
+		c, err := NewHashedCredential(username, hash)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %w", username, err)
+		}
+		cs = append(cs, c)
+	}
+	return NewWrapperWithProvider(cs), nil
+}
+
+// ParseHtpasswd parses credentials in the Apache htpasswd format, with one "username:hash" entry
+// per line. Only bcrypt and argon2 hashes are supported, as the other htpasswd formats are weak.
+func ParseHtpasswd(data []byte) ([]Credential, error) {
+	var credentials []Credential
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		username, hash, found := strings.Cut(entry, ":")
+		if !found || username == "" {
+			return nil, fmt.Errorf("line %d: expected username:hash", line)
+		}
+
+		c, err := NewHashedCredential(username, hash)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		credentials = append(credentials, c)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// NewHtpasswdFileProvider is a FileCredentialProvider for a file in the htpasswd format.
+// See ParseHtpasswd() for the supported formats.
+func NewHtpasswdFileProvider(path string) (*FileCredentialProvider, error) {
+	p := &FileCredentialProvider{path: path, parse: ParseHtpasswd}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func parsePasswordHash(hash string) (passwordHash, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+		return bcryptHash(hash), nil
+	case strings.HasPrefix(hash, "$argon2id$"), strings.HasPrefix(hash, "$argon2i$"):
+		return parseArgon2Hash(hash)
+	default:
+		return nil, errors.New("unsupported password hash format")
+	}
+}
+
+type bcryptHash []byte
+
+func (h bcryptHash) matches(password string) bool {
+	return bcrypt.CompareHashAndPassword(h, []byte(password)) == nil
+}
+
+type argon2Hash struct {
+	variant string
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2Hash(hash string) (passwordHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, errors.New("invalid argon2 hash: expected $variant$v=version$m=memory,t=time,p=threads$salt$hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("invalid argon2 hash: unsupported version %q", parts[2])
+	}
+
+	h := argon2Hash{variant: parts[1]}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2 hash parameters: %w", err)
+	}
+	if h.time == 0 || h.threads == 0 {
+		return nil, errors.New("invalid argon2 hash parameters: time and threads must be positive")
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, errors.New("invalid argon2 hash: could not decode key")
+	}
+
+	return h, nil
+}
+
+func (h argon2Hash) matches(password string) bool {
+	var key []byte
+	switch h.variant {
+	case "argon2id":
+		key = argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	default:
+		key = argon2.Key([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	}
+	return subtle.ConstantTimeCompare(key, h.key) == 1
+}
@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+)
+
+var _ = Describe("Hashed credentials", func() {
+	var (
+		bcryptHash string
+		argon2Hash string
+		handler    http.Handler
+	)
+
+	statusFor := func(wrapper *auth.Wrapper, username, password string) int {
+		request, err := http.NewRequest("GET", "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.SetBasicAuth(username, password)
+		recorder := httptest.NewRecorder()
+		wrapper.Wrap(handler).ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	BeforeEach(func() {
+		bcryptHash = string(must(bcrypt.GenerateFromPassword([]byte("bcrypt-secret"), bcrypt.MinCost)))
+
+		salt := []byte("some-random-salt")
+		key := argon2.IDKey([]byte("argon2-secret"), salt, 1, 1024, 1, 32)
+		argon2Hash = fmt.Sprintf("$argon2id$v=%d$m=1024,t=1,p=1$%s$%s", argon2.Version,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+	})
+
+	Describe("NewWrapperFromHashes()", func() {
+		It("accepts bcrypt and argon2 hashes", func() {
+			wrapper := must(auth.NewWrapperFromHashes(map[string]string{
+				"bcrypt-user": bcryptHash,
+				"argon2-user": argon2Hash,
+			}))
+
+			Expect(statusFor(wrapper, "bcrypt-user", "bcrypt-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "argon2-user", "argon2-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects wrong passwords", func() {
+			wrapper := must(auth.NewWrapperFromHashes(map[string]string{
+				"bcrypt-user": bcryptHash,
+				"argon2-user": argon2Hash,
+			}))
+
+			Expect(statusFor(wrapper, "bcrypt-user", "argon2-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "argon2-user", "bcrypt-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(statusFor(wrapper, "other-user", "bcrypt-secret")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("verifies a hash when no credential has the username, so the time taken does not reveal it", func() {
+			wrapper := must(auth.NewWrapperFromHashes(map[string]string{"bcrypt-user": bcryptHash}))
+			count, restore := auth.CountHashVerifications()
+			DeferCleanup(restore)
+
+			Expect(statusFor(wrapper, "other-user", "bcrypt-secret")).To(Equal(http.StatusUnauthorized))
+			Expect(count()).To(Equal(1))
+		})
+
+		It("only verifies the hash once for a username and password that match", func() {
+			wrapper := must(auth.NewWrapperFromHashes(map[string]string{"bcrypt-user": bcryptHash}))
+			count, restore := auth.CountHashVerifications()
+			DeferCleanup(restore)
+
+			Expect(statusFor(wrapper, "bcrypt-user", "bcrypt-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "bcrypt-user", "bcrypt-secret")).To(Equal(http.StatusCreated))
+			Expect(count()).To(Equal(1))
+
+			Expect(statusFor(wrapper, "bcrypt-user", "wrong")).To(Equal(http.StatusUnauthorized))
+			Expect(count()).To(Equal(2))
+		})
+
+		It("rejects the hash itself as a password", func() {
+			wrapper := must(auth.NewWrapperFromHashes(map[string]string{"bcrypt-user": bcryptHash}))
+			Expect(statusFor(wrapper, "bcrypt-user", bcryptHash)).To(Equal(http.StatusUnauthorized))
+		})
+
+		DescribeTable("invalid hashes",
+			func(hash, expectedError string) {
+				_, err := auth.NewWrapperFromHashes(map[string]string{"user": hash})
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("plaintext", "password", "unsupported password hash format"),
+			Entry("MD5", "$apr1$salt$hash", "unsupported password hash format"),
+			Entry("truncated bcrypt", "$2y$10$abc", "invalid bcrypt hash"),
+			Entry("argon2 without parameters", "$argon2id$v=19$salt$hash", "invalid argon2 hash"),
+			Entry("argon2 with unknown version", "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$aGFzaA", "unsupported version"),
+			Entry("argon2 with zero time", "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$aGFzaA", "must be positive"),
+		)
+	})
+
+	Describe("htpasswd", func() {
+		It("parses htpasswd entries, ignoring comments and blank lines", func() {
+			credentials := must(auth.ParseHtpasswd([]byte(fmt.Sprintf("# broker users\n\nbcrypt-user:%s\nargon2-user:%s\n", bcryptHash, argon2Hash))))
+			wrapper := auth.NewWrapperWithProvider(auth.StaticCredentials(credentials))
+
+			Expect(statusFor(wrapper, "bcrypt-user", "bcrypt-secret")).To(Equal(http.StatusCreated))
+			Expect(statusFor(wrapper, "argon2-user", "argon2-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("reports the line of an invalid entry", func() {
+			_, err := auth.ParseHtpasswd([]byte(fmt.Sprintf("bcrypt-user:%s\nnot-an-entry\n", bcryptHash)))
+			Expect(err).To(MatchError("line 2: expected username:hash"))
+		})
+
+		It("can be loaded from a file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), ".htpasswd")
+			Expect(os.WriteFile(path, []byte("bcrypt-user:"+bcryptHash+"\n"), 0o600)).To(Succeed())
+
+			wrapper := auth.NewWrapperWithProvider(must(auth.NewHtpasswdFileProvider(path)))
+			Expect(statusFor(wrapper, "bcrypt-user", "bcrypt-secret")).To(Equal(http.StatusCreated))
+		})
+	})
+
+	Describe("credential providers", func() {
+		It("accepts password hashes in a credentials file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "credentials.json")
+			Expect(os.WriteFile(path, []byte(fmt.Sprintf(`{"credentials":[{"username":"broker","password_hash":%q}]}`, bcryptHash)), 0o600)).To(Succeed())
+
+			wrapper := auth.NewWrapperWithProvider(must(auth.NewFileCredentialProvider(path)))
+			Expect(statusFor(wrapper, "broker", "bcrypt-secret")).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects a credentials file entry with both a password and a hash", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "credentials.json")
+			Expect(os.WriteFile(path, []byte(fmt.Sprintf(`{"credentials":[{"username":"broker","password":"p","password_hash":%q}]}`, bcryptHash)), 0o600)).To(Succeed())
+
+			_, err := auth.NewFileCredentialProvider(path)
+			Expect(err).To(MatchError(ContainSubstring("only one of password and password_hash")))
+		})
+
+		It("accepts password hashes in the environment", func() {
+			GinkgoT().Setenv("HASHED_USERNAME", "broker")
+			GinkgoT().Setenv("HASHED_PASSWORD_HASH", argon2Hash)
+
+			wrapper := auth.NewWrapperWithProvider(auth.NewEnvCredentialProvider("HASHED"))
+			Expect(statusFor(wrapper, "broker", "argon2-secret")).To(Equal(http.StatusCreated))
+		})
+	})
+})
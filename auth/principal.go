@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/pivotal-cf/brokerapi/v12/middlewares"
+)
+
+// Scheme is the authentication scheme that was used to authenticate a request
+type Scheme string
+
+const (
+	SchemeBasic      Scheme = "basic"
+	SchemeBearer     Scheme = "bearer"
+	SchemeClientCert Scheme = "client-certificate"
+	SchemeHMAC       Scheme = "hmac"
+)
+
+// Principal describes the authenticated client of a request. Name is the basic auth username,
+// the token subject, the client certificate common name, or the HMAC key ID. Label is the label
+// of the matched credential (see Credential.WithLabel()), the key ID that verified a token, or the
+// allowlist entry that matched a client certificate. Scope is nil when the client is not restricted
+// (see Credential.WithScope()).
+type Principal struct {
+	Name   string
+	Scheme Scheme
+	Label  string
+	Scope  *Scope
+}
+
+func AddPrincipalToContext(ctx context.Context, principal *Principal) context.Context {
+	if principal != nil {
+		return context.WithValue(ctx, middlewares.PrincipalKey, principal)
+	}
+	return ctx
+}
+
+// RetrievePrincipalFromContext returns the authenticated Principal, or nil if the request
+// was not authenticated by one of the wrappers in this package
+func RetrievePrincipalFromContext(ctx context.Context) *Principal {
+	if value := ctx.Value(middlewares.PrincipalKey); value != nil {
+		return value.(*Principal)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+)
+
+// Operation is a Service Broker API operation that a credential can be permitted to perform
+type Operation string
+
+const (
+	Catalog              Operation = "catalog"
+	Provision            Operation = "provision"
+	Update               Operation = "update"
+	Deprovision          Operation = "deprovision"
+	GetInstance          Operation = "get_instance"
+	LastOperation        Operation = "last_operation"
+	Bind                 Operation = "bind"
+	Unbind               Operation = "unbind"
+	GetBinding           Operation = "get_binding"
+	LastBindingOperation Operation = "last_binding_operation"
+)
+
+// ReadOnly are the operations that do not modify service instances or bindings
+var ReadOnly = []Operation{Catalog, GetInstance, LastOperation, GetBinding, LastBindingOperation}
+
+var allOperations = []Operation{Catalog, Provision, Update, Deprovision, GetInstance, LastOperation, Bind, Unbind, GetBinding, LastBindingOperation}
+
+// optionalServiceID are the operations for which the platform need not send a service_id
+var optionalServiceID = []Operation{GetInstance, LastOperation, GetBinding, LastBindingOperation}
+
+// Scope restricts what an authenticated client may do. An empty list places no restriction,
+// so the zero value permits everything. When ServiceIDs is populated, requests that do not
+// identify a service are denied, except for the fetch and last operation requests, for which
+// the service_id is optional. When PlanIDs is populated, any plan IDs in a request must be
+// allowed, and services in the catalog are only shown with their allowed plans.
+//
+// The service and plan IDs checked are those sent by the client. The library does not know which
+// service an existing instance belongs to, so a client permitted to use one service can update,
+// deprovision, bind or unbind an instance of another service by naming the permitted service, and
+// can fetch and poll any instance by leaving the service_id out. A ServiceBroker that needs to
+// prevent this must check the instance against the Principal in the request context.
+type Scope struct {
+	Operations []Operation
+	ServiceIDs []string
+	PlanIDs    []string
+}
+
+// Validate checks that the Scope only refers to known operations
+func (s Scope) Validate() error {
+	for _, o := range s.Operations {
+		if !slices.Contains(allOperations, o) {
+			return fmt.Errorf("unknown operation %q", o)
+		}
+	}
+	return nil
+}
+
+// AllowsOperation checks whether the operation may be performed, regardless of the service.
+// A nil Scope allows everything.
+func (s *Scope) AllowsOperation(operation Operation) bool {
+	return s == nil || len(s.Operations) == 0 || slices.Contains(s.Operations, operation)
+}
+
+// Allows checks whether the operation may be performed on the service and plans.
+// Empty plan IDs are ignored. A nil Scope allows everything.
+func (s *Scope) Allows(operation Operation, serviceID string, planIDs ...string) bool {
+	if s == nil {
+		return true
+	}
+
+	if !s.AllowsOperation(operation) {
+		return false
+	}
+
+	serviceOmitted := serviceID == "" && slices.Contains(optionalServiceID, operation)
+	if len(s.ServiceIDs) > 0 && !serviceOmitted && !slices.Contains(s.ServiceIDs, serviceID) {
+		return false
+	}
+
+	for _, planID := range planIDs {
+		if planID != "" && len(s.PlanIDs) > 0 && !slices.Contains(s.PlanIDs, planID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterCatalog returns the services and plans in the catalog that the Scope allows.
+// Services with no allowed plans are removed. A nil Scope returns the catalog unchanged.
+func (s *Scope) FilterCatalog(services []domain.Service) []domain.Service {
+	if s == nil || (len(s.ServiceIDs) == 0 && len(s.PlanIDs) == 0) {
+		return services
+	}
+
+	filtered := make([]domain.Service, 0, len(services))
+	for _, service := range services {
+		if len(s.ServiceIDs) > 0 && !slices.Contains(s.ServiceIDs, service.ID) {
+			continue
+		}
+
+		if len(s.PlanIDs) > 0 {
+			var plans []domain.ServicePlan
+			for _, plan := range service.Plans {
+				if slices.Contains(s.PlanIDs, plan.ID) {
+					plans = append(plans, plan)
+				}
+			}
+			if len(plans) == 0 {
+				continue
+			}
+			service.Plans = plans
+		}
+
+		filtered = append(filtered, service)
+	}
+
+	return filtered
+}
@@ -0,0 +1,121 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+)
+
+var _ = Describe("Scope", func() {
+	Describe("Allows()", func() {
+		It("allows everything when there is no scope", func() {
+			var scope *auth.Scope
+			Expect(scope.Allows(auth.Provision, "any-service", "any-plan")).To(BeTrue())
+			Expect(scope.AllowsOperation(auth.Deprovision)).To(BeTrue())
+		})
+
+		It("restricts operations", func() {
+			scope := &auth.Scope{Operations: auth.ReadOnly}
+			Expect(scope.Allows(auth.Catalog, "")).To(BeTrue())
+			Expect(scope.Allows(auth.GetInstance, "any-service")).To(BeTrue())
+			Expect(scope.Allows(auth.Provision, "any-service")).To(BeFalse())
+			Expect(scope.AllowsOperation(auth.Bind)).To(BeFalse())
+		})
+
+		It("restricts services, and denies requests that do not identify a service", func() {
+			scope := &auth.Scope{ServiceIDs: []string{"beta-service"}}
+			Expect(scope.Allows(auth.Provision, "beta-service")).To(BeTrue())
+			Expect(scope.Allows(auth.Provision, "ga-service")).To(BeFalse())
+			Expect(scope.Allows(auth.Deprovision, "")).To(BeFalse())
+		})
+
+		It("allows requests that leave out an optional service", func() {
+			scope := &auth.Scope{ServiceIDs: []string{"beta-service"}, PlanIDs: []string{"small"}}
+			Expect(scope.Allows(auth.LastOperation, "")).To(BeTrue())
+			Expect(scope.Allows(auth.GetInstance, "", "small")).To(BeTrue())
+			Expect(scope.Allows(auth.GetBinding, "", "large")).To(BeFalse())
+			Expect(scope.Allows(auth.LastBindingOperation, "ga-service")).To(BeFalse())
+		})
+
+		It("restricts plans, ignoring empty plan IDs", func() {
+			scope := &auth.Scope{PlanIDs: []string{"small"}}
+			Expect(scope.Allows(auth.Update, "any-service", "small", "")).To(BeTrue())
+			Expect(scope.Allows(auth.Update, "any-service", "small", "large")).To(BeFalse())
+		})
+	})
+
+	Describe("FilterCatalog()", func() {
+		services := []domain.Service{
+			{ID: "ga-service", Plans: []domain.ServicePlan{{ID: "ga-plan"}}},
+			{ID: "beta-service", Plans: []domain.ServicePlan{{ID: "beta-small"}, {ID: "beta-large"}}},
+		}
+
+		It("returns the catalog unchanged when there are no service or plan restrictions", func() {
+			Expect((&auth.Scope{Operations: auth.ReadOnly}).FilterCatalog(services)).To(Equal(services))
+		})
+
+		It("removes services and plans that are not allowed", func() {
+			filtered := (&auth.Scope{PlanIDs: []string{"beta-large"}}).FilterCatalog(services)
+			Expect(filtered).To(Equal([]domain.Service{
+				{ID: "beta-service", Plans: []domain.ServicePlan{{ID: "beta-large"}}},
+			}))
+			Expect(services[1].Plans).To(HaveLen(2))
+		})
+	})
+
+	Describe("scoped credentials", func() {
+		principalFor := func(provider auth.CredentialProvider, username, password string) (principal *auth.Principal) {
+			request := must(http.NewRequest("GET", "", nil))
+			request.SetBasicAuth(username, password)
+			auth.NewWrapperWithProvider(provider).WrapFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = auth.RetrievePrincipalFromContext(r.Context())
+			}).ServeHTTP(httptest.NewRecorder(), request)
+			return principal
+		}
+
+		It("reports the scope in the principal", func() {
+			scope := auth.Scope{ServiceIDs: []string{"beta-service"}}
+			principal := principalFor(auth.StaticCredentials{auth.NewCredential("staging", "secret").WithScope(scope)}, "staging", "secret")
+			Expect(principal.Scope).To(Equal(&scope))
+		})
+
+		It("reads scopes from a credentials file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "credentials.json")
+			Expect(os.WriteFile(path, []byte(`{"credentials":[{"username":"staging","password":"secret","scope":{"operations":["catalog","provision"],"service_ids":["beta-service"]}}]}`), 0o600)).To(Succeed())
+
+			principal := principalFor(must(auth.NewFileCredentialProvider(path)), "staging", "secret")
+			Expect(principal.Scope).To(Equal(&auth.Scope{
+				Operations: []auth.Operation{auth.Catalog, auth.Provision},
+				ServiceIDs: []string{"beta-service"},
+			}))
+		})
+
+		It("rejects unknown operations in a credentials file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "credentials.json")
+			Expect(os.WriteFile(path, []byte(`{"credentials":[{"username":"staging","password":"secret","scope":{"operations":["destroy"]}}]}`), 0o600)).To(Succeed())
+
+			_, err := auth.NewFileCredentialProvider(path)
+			Expect(err).To(MatchError(ContainSubstring(`credential 0: unknown operation "destroy"`)))
+		})
+
+		It("reads scopes from the environment", func() {
+			GinkgoT().Setenv("SCOPED_USERNAME", "staging")
+			GinkgoT().Setenv("SCOPED_PASSWORD", "secret")
+			GinkgoT().Setenv("SCOPED_OPERATIONS", "catalog, get_instance")
+			GinkgoT().Setenv("SCOPED_PLAN_IDS", "beta-small")
+
+			principal := principalFor(auth.NewEnvCredentialProvider("SCOPED"), "staging", "secret")
+			Expect(principal.Scope).To(Equal(&auth.Scope{
+				Operations: []auth.Operation{auth.Catalog, auth.GetInstance},
+				PlanIDs:    []string{"beta-small"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,146 @@
+// Command catalog-lint checks service broker catalog files for mistakes that would cause a platform
+// to reject the broker registration, using domain.ValidateCatalog(). A file may be JSON or YAML
+// (".yml" or ".yaml"), and may contain either a catalog response ({"services": [...]}) or an array
+// of services. The exit status is 1 if any problems are found, and 2 if a file cannot be read.
+//
+// Usage:
+//
+//	catalog-lint catalog.json [catalog.yml ...]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+const (
+	exitOK       = 0
+	exitProblems = 1
+	exitError    = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: catalog-lint <catalog file>...")
+		return exitError
+	}
+
+	status := exitOK
+	for _, path := range args {
+		services, err := load(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", path, err)
+			status = exitError
+			continue
+		}
+
+		for _, problem := range domain.ValidateCatalog(services) {
+			fmt.Fprintf(stdout, "%s: %s\n", path, problem)
+			status = max(status, exitProblems)
+		}
+	}
+	return status
+}
+
+func load(path string) ([]domain.Service, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var services []domain.Service
+		return services, decode(data, &services)
+	}
+
+	var catalog apiresponses.CatalogResponse
+	if err := decode(data, &catalog); err != nil {
+		return nil, err
+	}
+	if catalog.Services == nil {
+		return nil, fmt.Errorf(`no "services" found`)
+	}
+	return catalog.Services, nil
+}
+
+// decode rejects unknown fields, as they are usually misspelt field names
+func decode(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// yamlToJSON converts YAML to JSON, so that the JSON field names of the catalog types are used
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	converted, err := convertYAML(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(converted)
+}
+
+func convertYAML(value any) (any, error) {
+	switch value := value.(type) {
+	case map[string]any:
+		for k, v := range value {
+			converted, err := convertYAML(v)
+			if err != nil {
+				return nil, err
+			}
+			value[k] = converted
+		}
+		return value, nil
+	case map[any]any:
+		result := make(map[string]any, len(value))
+		for k, v := range value {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a string", k)
+			}
+			converted, err := convertYAML(v)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case []any:
+		for i, v := range value {
+			converted, err := convertYAML(v)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = converted
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("catalog-lint", func() {
+	var (
+		dir            string
+		stdout, stderr *gbytes.Buffer
+	)
+
+	writeFile := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(contents), 0o600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		stdout = gbytes.NewBuffer()
+		stderr = gbytes.NewBuffer()
+	})
+
+	It("accepts a valid JSON catalog", func() {
+		path := writeFile("catalog.json", `{"services":[{"id":"s1","name":"db","description":"A database","bindable":true,"plan_updateable":false,
+			"plans":[{"id":"p1","name":"small","description":"A small database"}]}]}`)
+
+		Expect(run([]string{path}, stdout, stderr)).To(Equal(exitOK))
+		Expect(stdout.Contents()).To(BeEmpty())
+	})
+
+	It("reports problems in a YAML catalog", func() {
+		path := writeFile("catalog.yml", `
+services:
+- id: s1
+  name: My Database
+  description: A database
+  plans:
+  - id: p1
+    name: small
+    schemas:
+      service_instance:
+        create:
+          parameters:
+            type: map
+`)
+
+		Expect(run([]string{path}, stdout, stderr)).To(Equal(exitProblems))
+		Expect(string(stdout.Contents())).To(Equal(
+			path + `: services[0].name: "My Database" is not CLI-friendly: use lowercase letters, digits, '-', '_' and '.' only` + "\n" +
+				path + ": services[0].plans[0].description: must not be empty\n" +
+				path + `: services[0].plans[0].schemas.service_instance.create.parameters: invalid schema: /type: unknown type "map"` + "\n"))
+	})
+
+	It("accepts an array of services", func() {
+		path := writeFile("services.json", `[{"id":"s1","name":"db","description":"A database","plans":[]}]`)
+
+		Expect(run([]string{path}, stdout, stderr)).To(Equal(exitProblems))
+		Expect(stdout).To(gbytes.Say(`services\[0\].plans: must have at least one plan`))
+	})
+
+	It("rejects unknown fields", func() {
+		path := writeFile("catalog.json", `{"services":[{"id":"s1","plan_updatable":true}]}`)
+
+		Expect(run([]string{path}, stdout, stderr)).To(Equal(exitError))
+		Expect(stderr).To(gbytes.Say(`unknown field "plan_updatable"`))
+	})
+
+	It("fails when a file cannot be read", func() {
+		Expect(run([]string{filepath.Join(dir, "missing.json")}, stdout, stderr)).To(Equal(exitError))
+		Expect(stderr).To(gbytes.Say("no such file"))
+	})
+
+	It("requires a file", func() {
+		Expect(run(nil, stdout, stderr)).To(Equal(exitError))
+		Expect(stderr).To(gbytes.Say("usage"))
+	})
+})
@@ -3,9 +3,15 @@ package brokerapi
 import (
 	"context"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/utils"
 )
 
+type Principal = auth.Principal
+
+type OriginatingIdentity = domain.OriginatingIdentity
+
 func AddServiceToContext(ctx context.Context, service *Service) context.Context {
 	return utils.AddServiceToContext(ctx, service)
 }
@@ -21,3 +27,19 @@ func AddServicePlanToContext(ctx context.Context, plan *ServicePlan) context.Con
 func RetrieveServicePlanFromContext(ctx context.Context) *ServicePlan {
 	return utils.RetrieveServicePlanFromContext(ctx)
 }
+
+func AddPreviousServicePlanToContext(ctx context.Context, plan *ServicePlan) context.Context {
+	return utils.AddPreviousServicePlanToContext(ctx, plan)
+}
+
+func RetrievePreviousServicePlanFromContext(ctx context.Context) *ServicePlan {
+	return utils.RetrievePreviousServicePlanFromContext(ctx)
+}
+
+func RetrievePrincipalFromContext(ctx context.Context) *Principal {
+	return auth.RetrievePrincipalFromContext(ctx)
+}
+
+func RetrieveOriginatingIdentityFromContext(ctx context.Context) *OriginatingIdentity {
+	return utils.RetrieveOriginatingIdentityFromContext(ctx)
+}
@@ -74,4 +74,12 @@ var _ = Describe("Context Utilities", func() {
 			})
 		})
 	})
+
+	Describe("Previous Plan Context", func() {
+		It("sets and retrieves the previous service plan in the context", func() {
+			ctx = brokerapi.AddPreviousServicePlanToContext(ctx, &brokerapi.ServicePlan{ID: "old-plan"})
+			Expect(brokerapi.RetrievePreviousServicePlanFromContext(ctx).ID).To(Equal("old-plan"))
+			Expect(brokerapi.RetrieveServicePlanFromContext(ctx)).To(BeNil())
+		})
+	})
 })
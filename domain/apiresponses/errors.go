@@ -21,6 +21,9 @@ const (
 	concurrentInstanceAccessMsg   = "instance is being updated and cannot be retrieved"
 	maintenanceInfoConflictMsg    = "passed maintenance_info does not match the catalog maintenance_info"
 	maintenanceInfoNilConflictMsg = "maintenance_info was passed, but the broker catalog contains no maintenance_info"
+	bindingNotSupportedMsg        = "the service plan is not bindable"
+	concurrencyErrorMsg           = "another operation for this service instance is in progress"
+	operationQueueFullMsg         = "too many operations are in progress, try again later"
 
 	instanceLimitReachedErrorKey  = "instance-limit-reached"
 	instanceAlreadyExistsErrorKey = "instance-already-exists"
@@ -34,6 +37,9 @@ const (
 	appGuidNotProvidedErrorKey    = "app-guid-not-provided"
 	concurrentAccessKey           = "get-instance-during-update"
 	maintenanceInfoConflictKey    = "maintenance-info-conflict"
+	bindingNotSupportedKey        = "binding-not-supported"
+	concurrencyErrorKey           = "concurrency-error"
+	operationQueueFullKey         = "operation-queue-full"
 )
 
 var (
@@ -91,4 +97,16 @@ var (
 	ErrMaintenanceInfoNilConflict = NewFailureResponseBuilder(
 		errors.New(maintenanceInfoNilConflictMsg), http.StatusUnprocessableEntity, maintenanceInfoConflictKey,
 	).WithErrorKey("MaintenanceInfoConflict").Build()
+
+	ErrBindingNotSupported = NewFailureResponseBuilder(
+		errors.New(bindingNotSupportedMsg), http.StatusBadRequest, bindingNotSupportedKey,
+	).Build()
+
+	ErrConcurrencyError = NewFailureResponseBuilder(
+		errors.New(concurrencyErrorMsg), http.StatusUnprocessableEntity, concurrencyErrorKey,
+	).WithErrorKey("ConcurrencyError").Build()
+
+	ErrOperationQueueFull = NewFailureResponseBuilder(
+		errors.New(operationQueueFullMsg), http.StatusServiceUnavailable, operationQueueFullKey,
+	).Build()
 )
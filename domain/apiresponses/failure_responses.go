@@ -15,6 +15,9 @@ type FailureResponse struct {
 	loggerAction  string
 	emptyResponse bool
 	errorKey      string
+
+	instanceUsable   *bool
+	updateRepeatable *bool
 }
 
 // NewFailureResponse returns an error of type FailureResponse.
@@ -37,8 +40,10 @@ func (f *FailureResponse) ErrorResponse() any {
 	}
 
 	return ErrorResponse{
-		Description: f.error.Error(),
-		Error:       f.errorKey,
+		Description:      f.error.Error(),
+		Error:            f.errorKey,
+		InstanceUsable:   f.instanceUsable,
+		UpdateRepeatable: f.updateRepeatable,
 	}
 }
 
@@ -67,6 +72,9 @@ func (f *FailureResponse) AppendErrorMessage(msg string) *FailureResponse {
 		loggerAction:  f.loggerAction,
 		emptyResponse: f.emptyResponse,
 		errorKey:      f.errorKey,
+
+		instanceUsable:   f.instanceUsable,
+		updateRepeatable: f.updateRepeatable,
 	}
 }
 
@@ -77,6 +85,9 @@ type FailureResponseBuilder struct {
 	loggerAction  string
 	emptyResponse bool
 	errorKey      string
+
+	instanceUsable   *bool
+	updateRepeatable *bool
 }
 
 // NewFailureResponseBuilder returns a pointer to a newly instantiated FailureResponseBuilder
@@ -104,6 +115,20 @@ func (f *FailureResponseBuilder) WithEmptyResponse() *FailureResponseBuilder {
 	return f
 }
 
+// WithInstanceUsable tells the platform whether the instance can still be used after a failed update or
+// deprovision. It is sent as the `instance_usable` field for API version 2.15 and later.
+func (f *FailureResponseBuilder) WithInstanceUsable(usable bool) *FailureResponseBuilder {
+	f.instanceUsable = &usable
+	return f
+}
+
+// WithUpdateRepeatable tells the platform whether a failed update can be tried again. It is sent as the
+// `update_repeatable` field for API version 2.15 and later.
+func (f *FailureResponseBuilder) WithUpdateRepeatable(repeatable bool) *FailureResponseBuilder {
+	f.updateRepeatable = &repeatable
+	return f
+}
+
 // Build returns the generated FailureResponse built using previously configured variables.
 func (f *FailureResponseBuilder) Build() *FailureResponse {
 	return &FailureResponse{
@@ -112,5 +137,8 @@ func (f *FailureResponseBuilder) Build() *FailureResponse {
 		loggerAction:  f.loggerAction,
 		emptyResponse: f.emptyResponse,
 		errorKey:      f.errorKey,
+
+		instanceUsable:   f.instanceUsable,
+		updateRepeatable: f.updateRepeatable,
 	}
 }
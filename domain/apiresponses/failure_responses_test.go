@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 )
 
@@ -30,6 +31,21 @@ var _ = Describe("FailureResponse", func() {
 			})
 		})
 
+		Context("when instance_usable and update_repeatable are provided", func() {
+			It("returns a ErrorResponse containing them", func() {
+				failureResponse := apiresponses.NewFailureResponseBuilder(errors.New("my error message"), http.StatusUnprocessableEntity, "log-key").
+					WithInstanceUsable(true).
+					WithUpdateRepeatable(false).
+					Build()
+				Expect(failureResponse.ErrorResponse()).To(Equal(apiresponses.ErrorResponse{
+					Description:      "my error message",
+					InstanceUsable:   domain.InstanceUsableValue(true),
+					UpdateRepeatable: domain.UpdateRepeatableValue(false),
+				}))
+				Expect(failureResponse.AppendErrorMessage("and more").ErrorResponse()).To(HaveField("InstanceUsable", HaveValue(BeTrue())))
+			})
+		})
+
 		Context("when created with empty response", func() {
 			It("returns an EmptyResponse", func() {
 				failureResponse := apiresponses.NewFailureResponseBuilder(errors.New("my error message"), http.StatusForbidden, "log-key").WithEmptyResponse().Build()
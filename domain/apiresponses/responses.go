@@ -20,8 +20,25 @@ import "github.com/pivotal-cf/brokerapi/v12/domain"
 type EmptyResponse struct{}
 
 type ErrorResponse struct {
-	Error       string `json:"error,omitempty"`
-	Description string `json:"description"`
+	Error            string `json:"error,omitempty"`
+	Description      string `json:"description"`
+	InstanceUsable   *bool  `json:"instance_usable,omitempty"`
+	UpdateRepeatable *bool  `json:"update_repeatable,omitempty"`
+}
+
+// ParameterValidationErrorResponse is returned when request parameters do not conform to the plan schema
+type ParameterValidationErrorResponse struct {
+	Error       string               `json:"error,omitempty"`
+	Description string               `json:"description"`
+	Violations  []ParameterViolation `json:"violations"`
+}
+
+// ParameterViolation describes one way in which parameters do not conform to a schema.
+// Path is a JSON pointer to the offending value within the parameters.
+type ParameterViolation struct {
+	Path    string `json:"path"`
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
 }
 
 type CatalogResponse struct {
@@ -53,8 +70,10 @@ type DeprovisionResponse struct {
 }
 
 type LastOperationResponse struct {
-	State       domain.LastOperationState `json:"state"`
-	Description string                    `json:"description,omitempty"`
+	State            domain.LastOperationState `json:"state"`
+	Description      string                    `json:"description,omitempty"`
+	InstanceUsable   *bool                     `json:"instance_usable,omitempty"`
+	UpdateRepeatable *bool                     `json:"update_repeatable,omitempty"`
 }
 
 type AsyncBindResponse struct {
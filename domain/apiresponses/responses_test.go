@@ -142,5 +142,16 @@ var _ = Describe("Error Response", func() {
 
 			Expect(json.Marshal(errorResponse)).To(MatchJSON(jsonString))
 		})
+
+		It("has the instance_usable and update_repeatable fields when they are set", func() {
+			errorResponse := apiresponses.ErrorResponse{
+				Description:      "a bad thing happened",
+				InstanceUsable:   domain.InstanceUsableValue(true),
+				UpdateRepeatable: domain.UpdateRepeatableValue(false),
+			}
+			jsonString := `{"description":"a bad thing happened","instance_usable":true,"update_repeatable":false}`
+
+			Expect(json.Marshal(errorResponse)).To(MatchJSON(jsonString))
+		})
 	})
 })
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/pivotal-cf/brokerapi/v12/internal/jsonschema"
+)
+
+var (
+	// cliFriendlyName is all lowercase, with no spaces, as recommended by the Open Service Broker API
+	cliFriendlyName = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+	semanticVersion = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+)
+
+// CatalogProblem is a mistake in a catalog that would cause a platform to reject it, or that would make
+// the broker awkward to use. Path locates the problem, for example "services[0].plans[1].name".
+type CatalogProblem struct {
+	Path    string
+	Message string
+}
+
+func (p CatalogProblem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidateCatalog checks a catalog against the rules of the Open Service Broker API, returning all the
+// problems found. It checks that IDs are present and unique (plan IDs across all services), that names
+// are present, unique and CLI-friendly, that descriptions are present, that every service has a plan,
+// and that plan schemas, maintenance_info versions and maximum_polling_duration values are valid.
+func ValidateCatalog(services []Service) []CatalogProblem {
+	var v catalogValidation
+
+	serviceIDs := make(map[string]string)
+	serviceNames := make(map[string]string)
+	planIDs := make(map[string]string)
+
+	for i, service := range services {
+		path := fmt.Sprintf("services[%d]", i)
+
+		v.required(path+".id", service.ID)
+		v.unique(path+".id", service.ID, serviceIDs)
+		v.name(path+".name", service.Name)
+		v.unique(path+".name", service.Name, serviceNames)
+		v.required(path+".description", service.Description)
+
+		for j, permission := range service.Requires {
+			if !slices.Contains([]RequiredPermission{PermissionRouteForwarding, PermissionSyslogDrain, PermissionVolumeMount}, permission) {
+				v.problem(fmt.Sprintf("%s.requires[%d]", path, j), "unknown permission %q", permission)
+			}
+		}
+
+		if service.DashboardClient != nil {
+			v.required(path+".dashboard_client.id", service.DashboardClient.ID)
+		}
+
+		if len(service.Plans) == 0 {
+			v.problem(path+".plans", "must have at least one plan")
+		}
+
+		planNames := make(map[string]string)
+		for j, plan := range service.Plans {
+			planPath := fmt.Sprintf("%s.plans[%d]", path, j)
+
+			v.required(planPath+".id", plan.ID)
+			v.unique(planPath+".id", plan.ID, planIDs)
+			v.name(planPath+".name", plan.Name)
+			v.unique(planPath+".name", plan.Name, planNames)
+			v.required(planPath+".description", plan.Description)
+
+			if plan.MaximumPollingDuration != nil && *plan.MaximumPollingDuration <= 0 {
+				v.problem(planPath+".maximum_polling_duration", "must be a positive number of seconds")
+			}
+
+			if plan.MaintenanceInfo != nil && plan.MaintenanceInfo.Version != "" && !semanticVersion.MatchString(plan.MaintenanceInfo.Version) {
+				v.problem(planPath+".maintenance_info.version", "%q is not a semantic version", plan.MaintenanceInfo.Version)
+			}
+
+			if plan.Schemas != nil {
+				v.schema(planPath+".schemas.service_instance.create.parameters", plan.Schemas.Instance.Create)
+				v.schema(planPath+".schemas.service_instance.update.parameters", plan.Schemas.Instance.Update)
+				v.schema(planPath+".schemas.service_binding.create.parameters", plan.Schemas.Binding.Create)
+			}
+		}
+	}
+
+	return v.problems
+}
+
+type catalogValidation struct {
+	problems []CatalogProblem
+}
+
+func (v *catalogValidation) problem(path, format string, args ...any) {
+	v.problems = append(v.problems, CatalogProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *catalogValidation) required(path, value string) {
+	if value == "" {
+		v.problem(path, "must not be empty")
+	}
+}
+
+func (v *catalogValidation) name(path, name string) {
+	if name == "" {
+		v.problem(path, "must not be empty")
+	} else if !cliFriendlyName.MatchString(name) {
+		v.problem(path, "%q is not CLI-friendly: use lowercase letters, digits, '-', '_' and '.' only", name)
+	}
+}
+
+// unique records the path of the first occurrence of each value, and reports any later occurrence
+func (v *catalogValidation) unique(path, value string, seen map[string]string) {
+	if value == "" {
+		return
+	}
+	if first, ok := seen[value]; ok {
+		v.problem(path, "%q is also used by %s", value, first)
+		return
+	}
+	seen[value] = path
+}
+
+func (v *catalogValidation) schema(path string, schema Schema) {
+	if len(schema.Parameters) == 0 {
+		return
+	}
+	if _, err := jsonschema.Compile(schema.Parameters); err != nil {
+		v.problem(path, "invalid schema: %s", err)
+	}
+}
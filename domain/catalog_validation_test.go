@@ -0,0 +1,121 @@
+package domain_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+)
+
+var _ = Describe("ValidateCatalog", func() {
+	var services []domain.Service
+
+	problems := func() []string {
+		var result []string
+		for _, p := range domain.ValidateCatalog(services) {
+			result = append(result, p.String())
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		services = []domain.Service{
+			{
+				ID:          "service-1",
+				Name:        "my-service",
+				Description: "A service",
+				Plans: []domain.ServicePlan{
+					{ID: "plan-1", Name: "small", Description: "A small plan"},
+					{ID: "plan-2", Name: "large", Description: "A large plan"},
+				},
+			},
+			{
+				ID:          "service-2",
+				Name:        "other.service_2",
+				Description: "Another service",
+				Plans: []domain.ServicePlan{
+					{ID: "plan-3", Name: "small", Description: "A small plan"},
+				},
+			},
+		}
+	})
+
+	It("accepts a valid catalog", func() {
+		Expect(domain.ValidateCatalog(services)).To(BeEmpty())
+	})
+
+	It("reports missing fields", func() {
+		services[0].ID = ""
+		services[0].Description = ""
+		services[0].Plans[1] = domain.ServicePlan{}
+
+		Expect(problems()).To(Equal([]string{
+			"services[0].id: must not be empty",
+			"services[0].description: must not be empty",
+			"services[0].plans[1].id: must not be empty",
+			"services[0].plans[1].name: must not be empty",
+			"services[0].plans[1].description: must not be empty",
+		}))
+	})
+
+	It("reports duplicate IDs and names", func() {
+		services[1].ID = "service-1"
+		services[1].Name = "my-service"
+		services[1].Plans[0].ID = "plan-2"
+		services[0].Plans[1].Name = "small"
+
+		Expect(problems()).To(Equal([]string{
+			`services[0].plans[1].name: "small" is also used by services[0].plans[0].name`,
+			`services[1].id: "service-1" is also used by services[0].id`,
+			`services[1].name: "my-service" is also used by services[0].name`,
+			`services[1].plans[0].id: "plan-2" is also used by services[0].plans[1].id`,
+		}))
+	})
+
+	It("reports names that are not CLI-friendly", func() {
+		services[0].Name = "My Service"
+		services[1].Plans[0].Name = "small-"
+
+		Expect(problems()).To(Equal([]string{
+			`services[0].name: "My Service" is not CLI-friendly: use lowercase letters, digits, '-', '_' and '.' only`,
+			`services[1].plans[0].name: "small-" is not CLI-friendly: use lowercase letters, digits, '-', '_' and '.' only`,
+		}))
+	})
+
+	It("reports services without plans", func() {
+		services[1].Plans = nil
+		Expect(problems()).To(Equal([]string{"services[1].plans: must have at least one plan"}))
+	})
+
+	It("reports invalid plan settings", func() {
+		duration := 0
+		services[0].Requires = []domain.RequiredPermission{domain.PermissionSyslogDrain, "log_drain"}
+		services[0].DashboardClient = &domain.ServiceDashboardClient{}
+		services[0].Plans[0].MaximumPollingDuration = &duration
+		services[0].Plans[0].MaintenanceInfo = &domain.MaintenanceInfo{Version: "1.2"}
+
+		Expect(problems()).To(Equal([]string{
+			`services[0].requires[1]: unknown permission "log_drain"`,
+			"services[0].dashboard_client.id: must not be empty",
+			"services[0].plans[0].maximum_polling_duration: must be a positive number of seconds",
+			`services[0].plans[0].maintenance_info.version: "1.2" is not a semantic version`,
+		}))
+	})
+
+	It("reports malformed schemas", func() {
+		services[0].Plans[0].Schemas = &domain.ServiceSchemas{
+			Instance: domain.ServiceInstanceSchema{
+				Create: domain.Schema{Parameters: map[string]any{"type": "object"}},
+				Update: domain.Schema{Parameters: map[string]any{"type": "map"}},
+			},
+			Binding: domain.ServiceBindingSchema{
+				Create: domain.Schema{Parameters: map[string]any{"$ref": "https://example.com/schema.json"}},
+			},
+		}
+
+		Expect(problems()).To(Equal([]string{
+			`services[0].plans[0].schemas.service_instance.update.parameters: invalid schema: /type: unknown type "map"`,
+			`services[0].plans[0].schemas.service_binding.create.parameters: invalid schema: /$ref: remote reference "https://example.com/schema.json" is not supported`,
+		}))
+	})
+})
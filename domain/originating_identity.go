@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	PlatformCloudFoundry = "cloudfoundry"
+	PlatformKubernetes   = "kubernetes"
+)
+
+// OriginatingIdentity is a parsed X-Broker-API-Originating-Identity header, which identifies the
+// platform user that initiated a request. Value is the decoded JSON object, whose contents depend
+// on the platform. See https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md#originating-identity-header
+type OriginatingIdentity struct {
+	Platform string
+	Value    json.RawMessage
+}
+
+// CloudFoundryIdentity is the originating identity value for the "cloudfoundry" platform
+type CloudFoundryIdentity struct {
+	UserID string `json:"user_id"`
+}
+
+// KubernetesIdentity is the originating identity value for the "kubernetes" platform
+type KubernetesIdentity struct {
+	Username string              `json:"username"`
+	UID      string              `json:"uid"`
+	Groups   []string            `json:"groups,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+// ParseOriginatingIdentity parses the value of a X-Broker-API-Originating-Identity header,
+// which is a platform name and a base64 encoded JSON object separated by a space
+func ParseOriginatingIdentity(header string) (*OriginatingIdentity, error) {
+	platform, encoded, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || platform == "" {
+		return nil, errors.New("originating identity must be a platform and a value separated by a space")
+	}
+
+	value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("originating identity value is not valid base64: %w", err)
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(value, &object); err != nil || object == nil {
+		return nil, errors.New("originating identity value must be a JSON object")
+	}
+
+	return &OriginatingIdentity{Platform: platform, Value: bytes.TrimSpace(value)}, nil
+}
+
+// CloudFoundry returns the identity of a Cloud Foundry user, or false if the platform is not "cloudfoundry"
+func (o OriginatingIdentity) CloudFoundry() (CloudFoundryIdentity, bool) {
+	var identity CloudFoundryIdentity
+	if o.Platform != PlatformCloudFoundry || json.Unmarshal(o.Value, &identity) != nil {
+		return CloudFoundryIdentity{}, false
+	}
+	return identity, true
+}
+
+// Kubernetes returns the identity of a Kubernetes user, or false if the platform is not "kubernetes"
+func (o OriginatingIdentity) Kubernetes() (KubernetesIdentity, bool) {
+	var identity KubernetesIdentity
+	if o.Platform != PlatformKubernetes || json.Unmarshal(o.Value, &identity) != nil {
+		return KubernetesIdentity{}, false
+	}
+	return identity, true
+}
+
+// User returns the Cloud Foundry user ID or Kubernetes username, or an empty string for other platforms
+func (o OriginatingIdentity) User() string {
+	if cf, ok := o.CloudFoundry(); ok {
+		return cf.UserID
+	}
+	if k8s, ok := o.Kubernetes(); ok {
+		return k8s.Username
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package domain_test
+
+import (
+	"encoding/base64"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+)
+
+var _ = Describe("OriginatingIdentity", func() {
+	encode := func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	Describe("ParseOriginatingIdentity", func() {
+		It("parses a Cloud Foundry identity", func() {
+			identity, err := domain.ParseOriginatingIdentity("cloudfoundry " + encode(`{"user_id":"683ea748-3092-4ff4-b656-39cacc4d5360"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(identity.Platform).To(Equal("cloudfoundry"))
+			Expect(identity.Value).To(MatchJSON(`{"user_id":"683ea748-3092-4ff4-b656-39cacc4d5360"}`))
+
+			cf, ok := identity.CloudFoundry()
+			Expect(ok).To(BeTrue())
+			Expect(cf).To(Equal(domain.CloudFoundryIdentity{UserID: "683ea748-3092-4ff4-b656-39cacc4d5360"}))
+			Expect(identity.User()).To(Equal("683ea748-3092-4ff4-b656-39cacc4d5360"))
+
+			_, ok = identity.Kubernetes()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("parses a Kubernetes identity", func() {
+			identity, err := domain.ParseOriginatingIdentity("kubernetes " + encode(`{"username":"duke","uid":"c2dde242-5ce4-11e7-988c-000c2946f14f","groups":["admin","dev"],"extra":{"mydata":["data1"]}}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			k8s, ok := identity.Kubernetes()
+			Expect(ok).To(BeTrue())
+			Expect(k8s).To(Equal(domain.KubernetesIdentity{
+				Username: "duke",
+				UID:      "c2dde242-5ce4-11e7-988c-000c2946f14f",
+				Groups:   []string{"admin", "dev"},
+				Extra:    map[string][]string{"mydata": {"data1"}},
+			}))
+			Expect(identity.User()).To(Equal("duke"))
+		})
+
+		It("parses other platforms without a user", func() {
+			identity, err := domain.ParseOriginatingIdentity("myplatform " + encode(`{"id":"someone"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(identity.Platform).To(Equal("myplatform"))
+			Expect(identity.User()).To(BeEmpty())
+		})
+
+		DescribeTable("malformed headers",
+			func(header, expectedError string) {
+				_, err := domain.ParseOriginatingIdentity(header)
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("empty", "", "must be a platform and a value"),
+			Entry("no value", "cloudfoundry", "must be a platform and a value"),
+			Entry("not base64", "cloudfoundry not-base64!", "not valid base64"),
+			Entry("not JSON", "cloudfoundry "+encode("user"), "must be a JSON object"),
+			Entry("JSON array", "cloudfoundry "+encode(`["user"]`), "must be a JSON object"),
+			Entry("JSON null", "cloudfoundry "+encode("null"), "must be a JSON object"),
+		)
+	})
+})
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PlatformContext is the decoded "context" object of a provision, update or bind request, which describes
+// where the instance or binding is being created. It is a *CloudFoundryContext, *KubernetesContext or
+// *OtherPlatformContext. See https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md#context-object
+type PlatformContext interface {
+	GetPlatform() string
+}
+
+// CloudFoundryContext is the context object for the "cloudfoundry" platform
+type CloudFoundryContext struct {
+	Platform                string            `json:"platform"`
+	OrganizationGUID        string            `json:"organization_guid"`
+	OrganizationName        string            `json:"organization_name,omitempty"`
+	OrganizationAnnotations map[string]string `json:"organization_annotations,omitempty"`
+	SpaceGUID               string            `json:"space_guid"`
+	SpaceName               string            `json:"space_name,omitempty"`
+	SpaceAnnotations        map[string]string `json:"space_annotations,omitempty"`
+	InstanceName            string            `json:"instance_name,omitempty"`
+	InstanceAnnotations     map[string]string `json:"instance_annotations,omitempty"`
+}
+
+// KubernetesContext is the context object for the "kubernetes" platform
+type KubernetesContext struct {
+	Platform     string `json:"platform"`
+	Namespace    string `json:"namespace"`
+	ClusterID    string `json:"clusterid"`
+	InstanceName string `json:"instance_name,omitempty"`
+}
+
+// OtherPlatformContext is the context object for a platform without a profile in the Open Service Broker API
+type OtherPlatformContext struct {
+	Platform string
+	Value    json.RawMessage
+}
+
+func (c *CloudFoundryContext) GetPlatform() string {
+	return c.Platform
+}
+
+func (c *KubernetesContext) GetPlatform() string {
+	return c.Platform
+}
+
+func (c *OtherPlatformContext) GetPlatform() string {
+	return c.Platform
+}
+
+// DecodePlatformContext decodes a context object into the type for its platform. It returns nil
+// if there is no context, and an error if the context is not a JSON object with a "platform".
+func DecodePlatformContext(rawContext json.RawMessage) (PlatformContext, error) {
+	if len(rawContext) == 0 {
+		return nil, nil
+	}
+
+	var header struct {
+		Platform string `json:"platform"`
+	}
+	if err := json.Unmarshal(rawContext, &header); err != nil {
+		return nil, fmt.Errorf("context must be a JSON object: %w", err)
+	}
+
+	var platformContext PlatformContext
+	switch header.Platform {
+	case "":
+		return nil, errors.New("context must have a platform")
+	case PlatformCloudFoundry:
+		platformContext = &CloudFoundryContext{}
+	case PlatformKubernetes:
+		platformContext = &KubernetesContext{}
+	default:
+		return &OtherPlatformContext{Platform: header.Platform, Value: rawContext}, nil
+	}
+
+	if err := json.Unmarshal(rawContext, platformContext); err != nil {
+		return nil, fmt.Errorf("invalid %s context: %w", header.Platform, err)
+	}
+	return platformContext, nil
+}
+
+// GetPlatformContext decodes the context of the request. See DecodePlatformContext().
+func (d ProvisionDetails) GetPlatformContext() (PlatformContext, error) {
+	return DecodePlatformContext(d.RawContext)
+}
+
+// GetPlatformContext decodes the context of the request. See DecodePlatformContext().
+func (d UpdateDetails) GetPlatformContext() (PlatformContext, error) {
+	return DecodePlatformContext(d.RawContext)
+}
+
+// GetPlatformContext decodes the context of the request. See DecodePlatformContext().
+func (d BindDetails) GetPlatformContext() (PlatformContext, error) {
+	return DecodePlatformContext(d.RawContext)
+}
@@ -0,0 +1,92 @@
+package domain_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+)
+
+var _ = Describe("PlatformContext", func() {
+	Describe("DecodePlatformContext", func() {
+		It("decodes a Cloud Foundry context", func() {
+			platformContext, err := domain.DecodePlatformContext(json.RawMessage(`{
+				"platform": "cloudfoundry",
+				"organization_guid": "an-org-guid",
+				"organization_name": "an-org",
+				"organization_annotations": {"company.com/cost-center": "1234"},
+				"space_guid": "a-space-guid",
+				"space_name": "a-space",
+				"space_annotations": {"company.com/team": "data"},
+				"instance_name": "an-instance",
+				"instance_annotations": {"company.com/tier": "gold"}
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platformContext).To(Equal(&domain.CloudFoundryContext{
+				Platform:                "cloudfoundry",
+				OrganizationGUID:        "an-org-guid",
+				OrganizationName:        "an-org",
+				OrganizationAnnotations: map[string]string{"company.com/cost-center": "1234"},
+				SpaceGUID:               "a-space-guid",
+				SpaceName:               "a-space",
+				SpaceAnnotations:        map[string]string{"company.com/team": "data"},
+				InstanceName:            "an-instance",
+				InstanceAnnotations:     map[string]string{"company.com/tier": "gold"},
+			}))
+		})
+
+		It("decodes a Kubernetes context", func() {
+			platformContext, err := domain.DecodePlatformContext(json.RawMessage(`{"platform":"kubernetes","namespace":"a-namespace","clusterid":"a-cluster","instance_name":"an-instance"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platformContext).To(Equal(&domain.KubernetesContext{
+				Platform:     "kubernetes",
+				Namespace:    "a-namespace",
+				ClusterID:    "a-cluster",
+				InstanceName: "an-instance",
+			}))
+		})
+
+		It("keeps the context of other platforms", func() {
+			platformContext, err := domain.DecodePlatformContext(json.RawMessage(`{"platform":"nomad","job":"a-job"}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platformContext.GetPlatform()).To(Equal("nomad"))
+			Expect(platformContext.(*domain.OtherPlatformContext).Value).To(MatchJSON(`{"platform":"nomad","job":"a-job"}`))
+		})
+
+		It("returns nil if there is no context", func() {
+			Expect(domain.DecodePlatformContext(nil)).To(BeNil())
+		})
+
+		It("rejects a context without a platform", func() {
+			_, err := domain.DecodePlatformContext(json.RawMessage(`{"namespace":"a-namespace"}`))
+			Expect(err).To(MatchError("context must have a platform"))
+		})
+
+		It("rejects a context that is not an object", func() {
+			_, err := domain.DecodePlatformContext(json.RawMessage(`"cloudfoundry"`))
+			Expect(err).To(MatchError(ContainSubstring("context must be a JSON object")))
+		})
+
+		It("rejects a context with fields of the wrong type", func() {
+			_, err := domain.DecodePlatformContext(json.RawMessage(`{"platform":"kubernetes","namespace":42}`))
+			Expect(err).To(MatchError(ContainSubstring("invalid kubernetes context")))
+		})
+	})
+
+	It("is available from the details of provision, update and bind requests", func() {
+		rawContext := json.RawMessage(`{"platform":"kubernetes","namespace":"a-namespace"}`)
+
+		for _, details := range []interface {
+			GetPlatformContext() (domain.PlatformContext, error)
+		}{
+			domain.ProvisionDetails{RawContext: rawContext},
+			domain.UpdateDetails{RawContext: rawContext},
+			domain.BindDetails{RawContext: rawContext},
+		} {
+			platformContext, err := details.GetPlatformContext()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platformContext).To(Equal(&domain.KubernetesContext{Platform: "kubernetes", Namespace: "a-namespace"}))
+		}
+	})
+})
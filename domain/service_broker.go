@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -57,6 +58,23 @@ type ServiceBroker interface {
 type LastOperation struct {
 	State       LastOperationState `json:"state"`
 	Description string             `json:"description"`
+
+	// InstanceUsable and UpdateRepeatable can be set when an update or deprovision has failed, to tell the
+	// platform whether the instance can still be used, and whether the update can be tried again. They are
+	// sent for API version 2.15 and later.
+	InstanceUsable   *bool `json:"instance_usable,omitempty"`
+	UpdateRepeatable *bool `json:"update_repeatable,omitempty"`
+
+	// RetryAfter is how long the platform should wait before polling again, sent as the Retry-After header
+	RetryAfter time.Duration `json:"-"`
+}
+
+func InstanceUsableValue(v bool) *bool {
+	return &v
+}
+
+func UpdateRepeatableValue(v bool) *bool {
+	return &v
 }
 
 type LastOperationState string
@@ -67,6 +85,13 @@ const (
 	Failed     LastOperationState = "failed"
 )
 
+// OperationJob is the work of an asynchronous operation, which a ServiceBroker can return instead of starting
+// the work itself. It is run by an operations.Engine, which answers last_operation requests for it. The job
+// should stop when ctx is cancelled, and can call progress to change the description of the operation. If the
+// job returns an error, the operation fails and the error is the description. An apiresponses.FailureResponse
+// built WithInstanceUsable or WithUpdateRepeatable sets those fields of the last operation.
+type OperationJob func(ctx context.Context, progress func(description string)) error
+
 type VolumeMount struct {
 	Driver       string       `json:"driver"`
 	ContainerDir string       `json:"container_dir"`
@@ -96,6 +121,8 @@ type ProvisionedServiceSpec struct {
 	DashboardURL  string
 	OperationData string
 	Metadata      InstanceMetadata
+	Job           OperationJob
+	RetryAfter    time.Duration
 }
 
 type InstanceMetadata struct {
@@ -112,6 +139,8 @@ type DeprovisionDetails struct {
 type DeprovisionServiceSpec struct {
 	IsAsync       bool
 	OperationData string
+	Job           OperationJob
+	RetryAfter    time.Duration
 }
 
 type GetInstanceDetailsSpec struct {
@@ -144,6 +173,8 @@ type UpdateServiceSpec struct {
 	DashboardURL  string
 	OperationData string
 	Metadata      InstanceMetadata
+	Job           OperationJob
+	RetryAfter    time.Duration
 }
 
 type FetchInstanceDetails struct {
@@ -187,6 +218,8 @@ type UnbindDetails struct {
 type UnbindSpec struct {
 	IsAsync       bool
 	OperationData string
+	Job           OperationJob
+	RetryAfter    time.Duration
 }
 
 type Binding struct {
@@ -200,6 +233,8 @@ type Binding struct {
 	VolumeMounts    []VolumeMount   `json:"volume_mounts"`
 	Endpoints       []Endpoint      `json:"endpoints,omitempty"`
 	Metadata        BindingMetadata `json:"metadata,omitempty"`
+	Job             OperationJob    `json:"-"`
+	RetryAfter      time.Duration   `json:"-"`
 }
 
 type BindingMetadata struct {
@@ -1,5 +1,7 @@
 package domain
 
+import "slices"
+
 const (
 	PermissionRouteForwarding = RequiredPermission("route_forwarding")
 	PermissionSyslogDrain     = RequiredPermission("syslog_drain")
@@ -8,6 +10,45 @@ const (
 	additionalMetadataName = "AdditionalMetadata"
 )
 
+// AsyncOperation is an operation that a ServicePlan may require to be performed asynchronously
+type AsyncOperation string
+
+const (
+	AsyncProvision   = AsyncOperation("provision")
+	AsyncUpdate      = AsyncOperation("update")
+	AsyncDeprovision = AsyncOperation("deprovision")
+	AsyncBind        = AsyncOperation("bind")
+	AsyncUnbind      = AsyncOperation("unbind")
+)
+
+var asyncOperations = []AsyncOperation{AsyncProvision, AsyncUpdate, AsyncDeprovision, AsyncBind, AsyncUnbind}
+
+// AsyncOperations is a set of AsyncOperation values. Unlike a slice, it keeps ServicePlan comparable.
+type AsyncOperations uint8
+
+// NewAsyncOperations returns the set of the operations
+func NewAsyncOperations(operations ...AsyncOperation) AsyncOperations {
+	var set AsyncOperations
+	for _, operation := range operations {
+		set |= operation.bit()
+	}
+	return set
+}
+
+// Has reports whether the operation is in the set
+func (s AsyncOperations) Has(operation AsyncOperation) bool {
+	bit := operation.bit()
+	return bit != 0 && s&bit != 0
+}
+
+func (o AsyncOperation) bit() AsyncOperations {
+	i := slices.Index(asyncOperations, o)
+	if i < 0 {
+		return 0
+	}
+	return 1 << i
+}
+
 type Service struct {
 	ID                   string                  `json:"id"`
 	Name                 string                  `json:"name"`
@@ -35,6 +76,18 @@ type ServicePlan struct {
 	PlanUpdatable          *bool                `json:"plan_updateable,omitempty"`
 	MaximumPollingDuration *int                 `json:"maximum_polling_duration,omitempty"`
 	MaintenanceInfo        *MaintenanceInfo     `json:"maintenance_info,omitempty"`
+
+	// AsyncRequired makes every operation on instances of the plan asynchronous only, and
+	// AsyncRequiredOperations makes only the operations in the set asynchronous only. The handlers
+	// reject requests for these operations with ErrAsyncRequired unless the platform accepts
+	// incomplete responses. They are not part of the catalog sent to the platform.
+	AsyncRequired           bool            `json:"-"`
+	AsyncRequiredOperations AsyncOperations `json:"-"`
+}
+
+// RequiresAsync reports whether the operation on instances of the plan can only be performed asynchronously
+func (p ServicePlan) RequiresAsync(operation AsyncOperation) bool {
+	return p.AsyncRequired || p.AsyncRequiredOperations.Has(operation)
 }
 
 type ServiceSchemas struct {
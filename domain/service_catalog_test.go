@@ -143,6 +143,37 @@ var _ = Describe("ServiceCatalog", func() {
 
 				Expect(json.Marshal(plan)).To(MatchJSON(jsonString))
 			})
+
+			It("does not encode the async-required operations", func() {
+				plan := domain.ServicePlan{
+					ID:                      "ID-1",
+					AsyncRequired:           true,
+					AsyncRequiredOperations: domain.NewAsyncOperations(domain.AsyncProvision),
+				}
+
+				Expect(json.Marshal(plan)).To(MatchJSON(`{"id":"ID-1","name":"","description":""}`))
+			})
+		})
+
+		Describe("RequiresAsync", func() {
+			It("requires every operation to be asynchronous when AsyncRequired is set", func() {
+				plan := domain.ServicePlan{AsyncRequired: true}
+				Expect(plan.RequiresAsync(domain.AsyncProvision)).To(BeTrue())
+				Expect(plan.RequiresAsync(domain.AsyncUnbind)).To(BeTrue())
+			})
+
+			It("requires only the listed operations to be asynchronous", func() {
+				plan := domain.ServicePlan{AsyncRequiredOperations: domain.NewAsyncOperations(domain.AsyncUpdate, domain.AsyncUnbind)}
+				Expect(plan.RequiresAsync(domain.AsyncUpdate)).To(BeTrue())
+				Expect(plan.RequiresAsync(domain.AsyncUnbind)).To(BeTrue())
+				Expect(plan.RequiresAsync(domain.AsyncProvision)).To(BeFalse())
+			})
+
+			It("keeps plans comparable", func() {
+				plan := domain.ServicePlan{ID: "ID-1", AsyncRequiredOperations: domain.NewAsyncOperations(domain.AsyncBind)}
+				Expect(plan == domain.ServicePlan{ID: "ID-1", AsyncRequiredOperations: domain.NewAsyncOperations(domain.AsyncBind)}).To(BeTrue())
+				Expect(plan == domain.ServicePlan{ID: "ID-1", AsyncRequiredOperations: domain.NewAsyncOperations(domain.AsyncUnbind)}).To(BeFalse())
+			})
 		})
 	})
 })
@@ -58,8 +58,9 @@ type FakeServiceBroker struct {
 
 	ReceivedContext bool
 
-	ServiceID string
-	PlanID    string
+	ServiceID       string
+	PlanID          string
+	MaintenanceInfo *brokerapi.MaintenanceInfo
 }
 
 type FakeAsyncServiceBroker struct {
@@ -81,6 +82,15 @@ const (
 func (fakeBroker *FakeServiceBroker) Services(ctx context.Context) ([]brokerapi.Service, error) {
 	fakeBroker.BrokerCalled = true
 
+	maintenanceInfo := fakeBroker.MaintenanceInfo
+	if maintenanceInfo == nil {
+		maintenanceInfo = &brokerapi.MaintenanceInfo{
+			Public: map[string]string{
+				"name": "foo",
+			},
+		}
+	}
+
 	if val, ok := ctx.Value(FakeBrokerContextDataKey).(bool); ok {
 		fakeBroker.ReceivedContext = val
 	}
@@ -105,11 +115,7 @@ func (fakeBroker *FakeServiceBroker) Services(ctx context.Context) ([]brokerapi.
 						Bullets:     []string{},
 						DisplayName: "Cassandra",
 					},
-					MaintenanceInfo: &brokerapi.MaintenanceInfo{
-						Public: map[string]string{
-							"name": "foo",
-						},
-					},
+					MaintenanceInfo: maintenanceInfo,
 					Schemas: &brokerapi.ServiceSchemas{
 						Instance: brokerapi.ServiceInstanceSchema{
 							Create: brokerapi.Schema{
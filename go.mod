@@ -7,6 +7,8 @@ require (
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.9.0
 	github.com/onsi/ginkgo/v2 v2.22.2
 	github.com/onsi/gomega v1.36.2
+	golang.org/x/crypto v0.31.0
+	gopkg.in/yaml.v3 v3.0.1
 	honnef.co/go/tools v0.5.1
 )
 
@@ -25,5 +27,4 @@ require (
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	golang.org/x/tools v0.28.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
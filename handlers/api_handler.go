@@ -7,8 +7,11 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
 )
 
 const (
@@ -16,6 +19,7 @@ const (
 	serviceIdMissingKey           = "service-id-missing"
 	planIdMissingKey              = "plan-id-missing"
 	unknownErrorKey               = "unknown-error"
+	forbiddenErrorKey             = "forbidden"
 )
 
 var (
@@ -23,15 +27,51 @@ var (
 	planIdError           = errors.New("plan_id missing")
 	invalidServiceIDError = errors.New("service-id not in the catalog")
 	invalidPlanIDError    = errors.New("plan-id not in the catalog")
+	forbiddenError        = errors.New("the credentials used are not permitted to perform this operation")
 )
 
 type APIHandler struct {
-	serviceBroker domain.ServiceBroker
-	logger        blog.Blog
+	serviceBroker      domain.ServiceBroker
+	logger             blog.Blog
+	validateParameters bool
+
+	skipMaintenanceInfoCheck bool
+	maxBodyBytes             int64
+	allowedPlatforms         []string
+	catalogResolution        CatalogResolution
+	operations               *operations.Engine
+	retryAfterPolicy         RetryAfterPolicy
+	onOperationTimeout       OperationTimeoutFunc
+	pollingDeadlines         *pollingDeadlines
+	schemas                  *compiledSchemas
+}
+
+// Option configures optional behaviour of an APIHandler
+type Option func(*APIHandler)
+
+// WithParameterValidation validates the parameters of provision, update and bind requests against the
+// JSON schemas of the plan in the catalog, and rejects requests with non-conforming parameters with
+// 400 Bad Request before the ServiceBroker is called
+func WithParameterValidation() Option {
+	return func(h *APIHandler) {
+		h.validateParameters = true
+	}
+}
+
+// WithoutMaintenanceInfoCheck stops the provision and update handlers from rejecting requests whose
+// maintenance_info does not match the catalog, leaving the check to the ServiceBroker
+func WithoutMaintenanceInfoCheck() Option {
+	return func(h *APIHandler) {
+		h.skipMaintenanceInfoCheck = true
+	}
 }
 
-func NewApiHandler(broker domain.ServiceBroker, logger *slog.Logger) APIHandler {
-	return APIHandler{serviceBroker: broker, logger: blog.New(logger)}
+func NewApiHandler(broker domain.ServiceBroker, logger *slog.Logger, opts ...Option) APIHandler {
+	h := APIHandler{serviceBroker: broker, logger: blog.New(logger), pollingDeadlines: newPollingDeadlines(), schemas: newCompiledSchemas()}
+	for _, o := range opts {
+		o(&h)
+	}
+	return h
 }
 
 func (h APIHandler) respond(w http.ResponseWriter, status int, requestIdentity string, response any) {
@@ -49,6 +89,28 @@ func (h APIHandler) respond(w http.ResponseWriter, status int, requestIdentity s
 	}
 }
 
+// scope returns the Scope of the authenticated principal, which is nil if the request is not restricted
+func scope(req *http.Request) *auth.Scope {
+	if principal := auth.RetrievePrincipalFromContext(req.Context()); principal != nil {
+		return principal.Scope
+	}
+	return nil
+}
+
+// checkScope responds with 403 Forbidden and returns false if the Scope of the authenticated principal
+// does not allow the operation on the service and plans
+func (h APIHandler) checkScope(w http.ResponseWriter, req *http.Request, logger blog.Blog, requestId string, operation auth.Operation, serviceID string, planIDs ...string) bool {
+	if scope(req).Allows(operation, serviceID, planIDs...) {
+		return true
+	}
+
+	logger.Error(forbiddenErrorKey, forbiddenError)
+	h.respond(w, http.StatusForbidden, requestId, apiresponses.ErrorResponse{
+		Description: forbiddenError.Error(),
+	})
+	return false
+}
+
 type brokerVersion struct {
 	Major int
 	Minor int
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+// checkAsyncRequired returns ErrAsyncRequired if the plan requires the operation to be performed
+// asynchronously, but the platform does not accept incomplete responses.
+func checkAsyncRequired(plan *domain.ServicePlan, operation domain.AsyncOperation, asyncAllowed bool) *apiresponses.FailureResponse {
+	if plan == nil || asyncAllowed || !plan.RequiresAsync(operation) {
+		return nil
+	}
+	return apiresponses.ErrAsyncRequired
+}
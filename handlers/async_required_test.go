@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+)
+
+var _ = Describe("Async-required plans", func() {
+	const asyncRequired = `{"error":"AsyncRequired","description":"This service plan requires client support for asynchronous service operations."}`
+
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		plan              domain.ServicePlan
+	)
+
+	newHandler := func() handlers.APIHandler {
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Bindable: true, Plans: []domain.ServicePlan{plan}}}, nil)
+		return handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)))
+	}
+
+	provision := func(query string) *httptest.ResponseRecorder {
+		return serve(newHandler().Provision, http.MethodPut, "/v2/service_instances/an-instance?"+query, `{"service_id":"a-service","plan_id":"a-plan"}`)
+	}
+	update := func(query string) *httptest.ResponseRecorder {
+		return serve(newHandler().Update, http.MethodPatch, "/v2/service_instances/an-instance?"+query, `{"service_id":"a-service","plan_id":"a-plan","previous_values":{"plan_id":"a-plan"}}`)
+	}
+	deprovision := func(query string) *httptest.ResponseRecorder {
+		return serve(newHandler().Deprovision, http.MethodDelete, "/v2/service_instances/an-instance?"+query+"&service_id=a-service&plan_id=a-plan", "")
+	}
+	bind := func(query string) *httptest.ResponseRecorder {
+		return serve(newHandler().Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding?"+query, `{"service_id":"a-service","plan_id":"a-plan"}`)
+	}
+	unbind := func(query string) *httptest.ResponseRecorder {
+		return serve(newHandler().Unbind, http.MethodDelete, "/v2/service_instances/an-instance/service_bindings/a-binding?"+query+"&service_id=a-service&plan_id=a-plan", "")
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		plan = domain.ServicePlan{ID: "a-plan"}
+	})
+
+	When("the plan requires every operation to be asynchronous", func() {
+		BeforeEach(func() {
+			plan.AsyncRequired = true
+		})
+
+		It("rejects requests that do not accept incomplete responses", func() {
+			for _, request := range []func(string) *httptest.ResponseRecorder{provision, update, deprovision, bind, unbind} {
+				response := request("accepts_incomplete=false")
+				Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(response.Body.String()).To(MatchJSON(asyncRequired))
+			}
+
+			Expect(fakeServiceBroker.ProvisionCallCount()).To(BeZero())
+			Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+			Expect(fakeServiceBroker.DeprovisionCallCount()).To(BeZero())
+			Expect(fakeServiceBroker.BindCallCount()).To(BeZero())
+			Expect(fakeServiceBroker.UnbindCallCount()).To(BeZero())
+		})
+
+		It("calls the broker when incomplete responses are accepted", func() {
+			Expect(provision("accepts_incomplete=true").Code).To(Equal(http.StatusCreated))
+			Expect(update("accepts_incomplete=true").Code).To(Equal(http.StatusOK))
+			Expect(deprovision("accepts_incomplete=true").Code).To(Equal(http.StatusOK))
+			Expect(bind("accepts_incomplete=true").Code).To(Equal(http.StatusCreated))
+			Expect(unbind("accepts_incomplete=true").Code).To(Equal(http.StatusOK))
+
+			_, _, _, asyncAllowed := fakeServiceBroker.ProvisionArgsForCall(0)
+			Expect(asyncAllowed).To(BeTrue())
+		})
+	})
+
+	When("the plan requires some operations to be asynchronous", func() {
+		BeforeEach(func() {
+			plan.AsyncRequiredOperations = domain.NewAsyncOperations(domain.AsyncProvision, domain.AsyncUnbind)
+		})
+
+		It("rejects only those operations", func() {
+			Expect(provision("").Body.String()).To(MatchJSON(asyncRequired))
+			Expect(unbind("").Body.String()).To(MatchJSON(asyncRequired))
+
+			Expect(update("").Code).To(Equal(http.StatusOK))
+			Expect(deprovision("").Code).To(Equal(http.StatusOK))
+			Expect(bind("").Code).To(Equal(http.StatusCreated))
+		})
+	})
+
+	It("does not apply to plans that are not in the catalog", func() {
+		plan.AsyncRequired = true
+
+		response := serve(newHandler().Deprovision, http.MethodDelete, "/v2/service_instances/an-instance?service_id=a-service&plan_id=other-plan", "")
+		Expect(response.Code).To(Equal(http.StatusOK))
+	})
+})
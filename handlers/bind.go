@@ -6,10 +6,12 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
 )
 
 const (
@@ -17,6 +19,14 @@ const (
 	invalidBindDetailsErrorKey = "invalid-bind-details"
 )
 
+// planBindable reports whether instances of the plan can be bound. The setting of the plan overrides that of the service.
+func planBindable(service *domain.Service, plan *domain.ServicePlan) bool {
+	if plan.Bindable != nil {
+		return *plan.Bindable
+	}
+	return service.Bindable
+}
+
 func (h APIHandler) Bind(w http.ResponseWriter, req *http.Request) {
 	instanceID := req.PathValue("instance_id")
 	bindingID := req.PathValue("binding_id")
@@ -32,11 +42,8 @@ func (h APIHandler) Bind(w http.ResponseWriter, req *http.Request) {
 	requestId := fmt.Sprintf("%v", req.Context().Value(middlewares.RequestIdentityKey))
 
 	var details domain.BindDetails
-	if err := json.NewDecoder(req.Body).Decode(&details); err != nil {
-		logger.Error(invalidBindDetailsErrorKey, err)
-		h.respond(w, http.StatusUnprocessableEntity, requestId, apiresponses.ErrorResponse{
-			Description: err.Error(),
-		})
+	if err := h.decodeDetails(w, req, &details); err != nil {
+		h.respondDecodeError(w, req, logger, requestId, invalidBindDetailsErrorKey, err)
 		return
 	}
 
@@ -56,12 +63,60 @@ func (h APIHandler) Bind(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := h.checkPlatform(details); err != nil {
+		logger.Error(platformNotAllowedKey, err)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+		return
+	}
+
+	if !h.checkScope(w, req, logger, requestId, auth.Bind, details.ServiceID, details.PlanID) {
+		return
+	}
+
+	service, plan := h.findInCatalog(req.Context(), details.ServiceID, details.PlanID)
+	if service == nil {
+		logger.Error(invalidServiceID, invalidServiceIDError)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: invalidServiceIDError.Error(),
+		})
+		return
+	}
+	req = req.WithContext(utils.AddServiceToContext(req.Context(), service))
+
+	if plan == nil {
+		logger.Error(invalidPlanID, invalidPlanIDError)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: invalidPlanIDError.Error(),
+		})
+		return
+	}
+	req = req.WithContext(utils.AddServicePlanToContext(req.Context(), plan))
+
+	if !planBindable(service, plan) {
+		failure := apiresponses.ErrBindingNotSupported
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
+	if plan.Schemas != nil && !h.checkParameters(w, logger, requestId, bindLogKey, plan.ID, plan.Schemas.Binding.Create, details.RawParameters, true) {
+		return
+	}
+
+	if failure := checkAsyncRequired(plan, domain.AsyncBind, asyncAllowed); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
 	binding, err := h.serviceBroker.Bind(req.Context(), instanceID, bindingID, details, asyncAllowed)
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			statusCode := err.ValidatedStatusCode(slog.New(logger))
-			errorResponse := err.ErrorResponse()
+			errorResponse := failureResponse(err, getAPIVersion(req))
 			if err == apiresponses.ErrInstanceDoesNotExist {
 				// work around ErrInstanceDoesNotExist having different pre-refactor behaviour to other actions
 				errorResponse = apiresponses.ErrorResponse{
@@ -80,6 +135,16 @@ func (h APIHandler) Bind(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if binding.Job != nil && !binding.AlreadyExists {
+		operationData, err := h.startJob(domain.AsyncBind, instanceID, bindingID, binding.Job, asyncAllowed)
+		if err != nil {
+			h.respondError(w, req, logger, requestId, err)
+			return
+		}
+		binding.IsAsync = true
+		binding.OperationData = operationData
+	}
+
 	var metadata any
 	if !binding.Metadata.IsEmpty() {
 		metadata = binding.Metadata
@@ -99,6 +164,8 @@ func (h APIHandler) Bind(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if binding.IsAsync {
+		h.pollingDeadlines.start(plan, domain.AsyncBind, instanceID, bindingID, binding.OperationData)
+		setRetryAfter(w, h.retryAfter(binding.RetryAfter, 0))
 		h.respond(w, http.StatusAccepted, requestId, apiresponses.AsyncBindResponse{
 			OperationData: binding.OperationData,
 		})
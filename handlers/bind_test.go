@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
+)
+
+var _ = Describe("Bind", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		service           domain.Service
+	)
+
+	bind := func(body string) *httptest.ResponseRecorder {
+		fakeServiceBroker.ServicesReturns([]domain.Service{service}, nil)
+		apiHandler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)))
+		return serve(apiHandler.Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding", body)
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		service = domain.Service{
+			ID:       "a-service",
+			Bindable: true,
+			Plans:    []domain.ServicePlan{{ID: "a-plan"}},
+		}
+	})
+
+	It("adds the service and plan to the context", func() {
+		Expect(bind(`{"service_id":"a-service","plan_id":"a-plan"}`).Code).To(Equal(http.StatusCreated))
+
+		ctx, _, _, _, _ := fakeServiceBroker.BindArgsForCall(0)
+		Expect(utils.RetrieveServiceFromContext(ctx).ID).To(Equal("a-service"))
+		Expect(utils.RetrieveServicePlanFromContext(ctx).ID).To(Equal("a-plan"))
+	})
+
+	It("rejects a service that is not in the catalog", func() {
+		response := bind(`{"service_id":"other-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"service-id not in the catalog"}`))
+		Expect(fakeServiceBroker.BindCallCount()).To(BeZero())
+	})
+
+	It("rejects a plan that does not belong to the service", func() {
+		response := bind(`{"service_id":"a-service","plan_id":"other-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"plan-id not in the catalog"}`))
+		Expect(fakeServiceBroker.BindCallCount()).To(BeZero())
+	})
+
+	It("rejects a service that is not bindable", func() {
+		service.Bindable = false
+
+		response := bind(`{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"the service plan is not bindable"}`))
+		Expect(fakeServiceBroker.BindCallCount()).To(BeZero())
+	})
+
+	It("lets the plan override the service", func() {
+		service.Plans[0].Bindable = domain.BindableValue(false)
+		Expect(bind(`{"service_id":"a-service","plan_id":"a-plan"}`).Code).To(Equal(http.StatusBadRequest))
+
+		service.Bindable = false
+		service.Plans[0].Bindable = domain.BindableValue(true)
+		Expect(bind(`{"service_id":"a-service","plan_id":"a-plan"}`).Code).To(Equal(http.StatusCreated))
+	})
+})
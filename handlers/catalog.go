@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
 )
@@ -15,12 +16,20 @@ func (h APIHandler) Catalog(w http.ResponseWriter, req *http.Request) {
 	logger := h.logger.Session(req.Context(), getCatalogLogKey)
 	requestId := fmt.Sprintf("%v", req.Context().Value(middlewares.RequestIdentityKey))
 
+	if !scope(req).AllowsOperation(auth.Catalog) {
+		logger.Error(forbiddenErrorKey, forbiddenError)
+		h.respond(w, http.StatusForbidden, requestId, apiresponses.ErrorResponse{
+			Description: forbiddenError.Error(),
+		})
+		return
+	}
+
 	services, err := h.serviceBroker.Services(req.Context())
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
@@ -31,7 +40,7 @@ func (h APIHandler) Catalog(w http.ResponseWriter, req *http.Request) {
 	}
 
 	catalog := apiresponses.CatalogResponse{
-		Services: services,
+		Services: scope(req).FilterCatalog(services),
 	}
 
 	h.respond(w, http.StatusOK, requestId, catalog)
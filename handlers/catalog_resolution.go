@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
+)
+
+// CatalogResolution is what the Deprovision, Unbind, LastOperation and GetInstance handlers do when
+// the service_id or plan_id of a request is not in the catalog, for example because the plan has been
+// retired. It also applies to the plan_id of an Update that does not change the plan. Services and plans
+// that are in the catalog are always added to the request context.
+type CatalogResolution int
+
+const (
+	// CatalogResolutionPassThrough calls the ServiceBroker without comment. This is the default.
+	CatalogResolutionPassThrough CatalogResolution = iota
+
+	// CatalogResolutionWarn logs a warning, and then calls the ServiceBroker
+	CatalogResolutionWarn
+
+	// CatalogResolutionReject responds with 400 Bad Request without calling the ServiceBroker
+	CatalogResolutionReject
+)
+
+// WithCatalogResolution sets what happens when the service_id or plan_id of a deprovision, unbind,
+// last operation or get instance request, or the plan_id of an update that does not change the plan,
+// is not in the catalog
+func WithCatalogResolution(resolution CatalogResolution) Option {
+	return func(h *APIHandler) {
+		h.catalogResolution = resolution
+	}
+}
+
+// findInCatalog returns the service and plan with the IDs from the catalog of the ServiceBroker. Either
+// is nil if it is not in the catalog, for example because it has been retired, and the plan is nil if
+// the service is. The checks of plan settings, such as checkAsyncRequired, planUpdatable and the polling
+// deadlines, treat a nil plan as a plan with no settings and leave the decision to the ServiceBroker.
+func (h APIHandler) findInCatalog(ctx context.Context, serviceID, planID string) (*domain.Service, *domain.ServicePlan) {
+	services, _ := h.serviceBroker.Services(ctx)
+	for _, service := range services {
+		if service.ID == serviceID {
+			return &service, findPlan(&service, planID)
+		}
+	}
+	return nil, nil
+}
+
+// findPlan returns the plan of the service with the ID
+func findPlan(service *domain.Service, planID string) *domain.ServicePlan {
+	for _, plan := range service.Plans {
+		if plan.ID == planID {
+			return &plan
+		}
+	}
+	return nil
+}
+
+// resolveCatalog finds the service and plan in the catalog and adds them to the request context. Empty
+// IDs are ignored, as they are optional for some endpoints. It responds and returns false if the request
+// should be rejected.
+func (h APIHandler) resolveCatalog(w http.ResponseWriter, req *http.Request, logger blog.Blog, requestId, serviceID, planID string) (*http.Request, *domain.ServicePlan, bool) {
+	if serviceID == "" && planID == "" {
+		return req, nil, true
+	}
+
+	service, plan := h.findInCatalog(req.Context(), serviceID, planID)
+	if service != nil {
+		req = req.WithContext(utils.AddServiceToContext(req.Context(), service))
+	} else if serviceID != "" && !h.unknownID(w, logger, requestId, invalidServiceID, invalidServiceIDError) {
+		return req, nil, false
+	}
+
+	if plan != nil {
+		req = req.WithContext(utils.AddServicePlanToContext(req.Context(), plan))
+	} else if planID != "" && service != nil && !h.unknownID(w, logger, requestId, invalidPlanID, invalidPlanIDError) {
+		return req, nil, false
+	}
+
+	return req, plan, true
+}
+
+// unknownID warns about or rejects an ID that is not in the catalog, and returns false if it was rejected
+func (h APIHandler) unknownID(w http.ResponseWriter, logger blog.Blog, requestId, key string, err error) bool {
+	switch h.catalogResolution {
+	case CatalogResolutionReject:
+		logger.Error(key, err)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+		return false
+	case CatalogResolutionWarn:
+		logger.Warn(key, err)
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
+)
+
+var _ = Describe("Catalog resolution", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		logs              *bytes.Buffer
+		opts              []handlers.Option
+	)
+
+	newHandler := func() handlers.APIHandler {
+		return handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(logs, nil)), opts...)
+	}
+
+	deprovision := func(query string) *httptest.ResponseRecorder {
+		return serve(newHandler().Deprovision, http.MethodDelete, "/v2/service_instances/an-instance?"+query, "")
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Plans: []domain.ServicePlan{{ID: "a-plan"}}}}, nil)
+		logs = new(bytes.Buffer)
+		opts = nil
+	})
+
+	It("adds the service and plan to the context", func() {
+		var contexts []context.Context
+
+		Expect(deprovision("service_id=a-service&plan_id=a-plan").Code).To(Equal(http.StatusOK))
+		ctx, _, _, _ := fakeServiceBroker.DeprovisionArgsForCall(0)
+		contexts = append(contexts, ctx)
+
+		Expect(serve(newHandler().Unbind, http.MethodDelete, "/v2/service_instances/an-instance/service_bindings/a-binding?service_id=a-service&plan_id=a-plan", "").Code).To(Equal(http.StatusOK))
+		ctx, _, _, _, _ = fakeServiceBroker.UnbindArgsForCall(0)
+		contexts = append(contexts, ctx)
+
+		Expect(serve(newHandler().LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?service_id=a-service&plan_id=a-plan", "").Code).To(Equal(http.StatusOK))
+		ctx, _, _ = fakeServiceBroker.LastOperationArgsForCall(0)
+		contexts = append(contexts, ctx)
+
+		Expect(serve(newHandler().GetInstance, http.MethodGet, "/v2/service_instances/an-instance?service_id=a-service&plan_id=a-plan", "").Code).To(Equal(http.StatusOK))
+		ctx, _, _ = fakeServiceBroker.GetInstanceArgsForCall(0)
+		contexts = append(contexts, ctx)
+
+		for _, ctx := range contexts {
+			Expect(utils.RetrieveServiceFromContext(ctx).ID).To(Equal("a-service"))
+			Expect(utils.RetrieveServicePlanFromContext(ctx).ID).To(Equal("a-plan"))
+		}
+	})
+
+	It("passes unknown IDs to the broker by default", func() {
+		Expect(deprovision("service_id=a-service&plan_id=retired-plan").Code).To(Equal(http.StatusOK))
+
+		ctx, _, _, _ := fakeServiceBroker.DeprovisionArgsForCall(0)
+		Expect(utils.RetrieveServiceFromContext(ctx).ID).To(Equal("a-service"))
+		Expect(utils.RetrieveServicePlanFromContext(ctx)).To(BeNil())
+		Expect(logs.String()).NotTo(ContainSubstring("invalid-plan-id"))
+	})
+
+	It("does not look up IDs that are not in the request", func() {
+		Expect(serve(newHandler().LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation", "").Code).To(Equal(http.StatusOK))
+		Expect(fakeServiceBroker.ServicesCallCount()).To(BeZero())
+	})
+
+	When("configured to warn", func() {
+		BeforeEach(func() {
+			opts = []handlers.Option{handlers.WithCatalogResolution(handlers.CatalogResolutionWarn)}
+		})
+
+		It("logs a warning and calls the broker", func() {
+			Expect(deprovision("service_id=a-service&plan_id=retired-plan").Code).To(Equal(http.StatusOK))
+			Expect(fakeServiceBroker.DeprovisionCallCount()).To(Equal(1))
+			Expect(logs.String()).To(SatisfyAll(
+				ContainSubstring(`"level":"WARN"`),
+				ContainSubstring(`"msg":"deprovision.invalid-plan-id"`),
+				ContainSubstring(`"error":"plan-id not in the catalog"`),
+			))
+		})
+	})
+
+	When("configured to reject", func() {
+		BeforeEach(func() {
+			opts = []handlers.Option{handlers.WithCatalogResolution(handlers.CatalogResolutionReject)}
+		})
+
+		It("rejects a service that is not in the catalog", func() {
+			response := deprovision("service_id=retired-service&plan_id=a-plan")
+			Expect(response.Code).To(Equal(http.StatusBadRequest))
+			Expect(response.Body.String()).To(MatchJSON(`{"description":"service-id not in the catalog"}`))
+			Expect(fakeServiceBroker.DeprovisionCallCount()).To(BeZero())
+		})
+
+		It("rejects a plan that is not in the catalog", func() {
+			response := serve(newHandler().LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?service_id=a-service&plan_id=retired-plan", "")
+			Expect(response.Code).To(Equal(http.StatusBadRequest))
+			Expect(response.Body.String()).To(MatchJSON(`{"description":"plan-id not in the catalog"}`))
+			Expect(fakeServiceBroker.LastOperationCallCount()).To(BeZero())
+		})
+
+		It("accepts requests without the optional IDs", func() {
+			Expect(serve(newHandler().GetInstance, http.MethodGet, "/v2/service_instances/an-instance", "").Code).To(Equal(http.StatusOK))
+			Expect(fakeServiceBroker.GetInstanceCallCount()).To(Equal(1))
+		})
+	})
+})
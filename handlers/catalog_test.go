@@ -8,6 +8,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
@@ -88,6 +89,49 @@ var _ = Describe("Services", func() {
 		body := fakeResponseWriter.WriteArgsForCall(0)
 		Expect(body).To(MatchJSON(`{"description":"TODO"}`))
 	})
+
+	When("the credentials are scoped", func() {
+		withScope := func(request *http.Request, scope auth.Scope) *http.Request {
+			return request.WithContext(auth.AddPrincipalToContext(request.Context(), &auth.Principal{Name: "staging", Scope: &scope}))
+		}
+
+		BeforeEach(func() {
+			fakeServiceBroker.ServicesReturns([]domain.Service{
+				{ID: "ga-service", Plans: []domain.ServicePlan{{ID: "ga-plan"}}},
+				{ID: "beta-service", Plans: []domain.ServicePlan{{ID: "beta-small"}, {ID: "beta-large"}}},
+			}, nil)
+		})
+
+		It("only returns the permitted services and plans", func() {
+			request := withScope(newServicesRequest(), auth.Scope{
+				ServiceIDs: []string{"beta-service"},
+				PlanIDs:    []string{"beta-small"},
+			})
+
+			apiHandler.Catalog(fakeResponseWriter, request)
+
+			Expect(fakeResponseWriter.WriteHeaderArgsForCall(0)).To(Equal(http.StatusOK))
+			Expect(fakeResponseWriter.WriteArgsForCall(0)).To(MatchJSON(`{
+				"services": [{
+					"id": "beta-service",
+					"name": "",
+					"description": "",
+					"bindable": false,
+					"plan_updateable": false,
+					"plans": [{"id": "beta-small", "name": "", "description": ""}]
+				}]
+			}`))
+		})
+
+		It("responds with Forbidden when the catalog is not a permitted operation", func() {
+			request := withScope(newServicesRequest(), auth.Scope{Operations: []auth.Operation{auth.Provision}})
+
+			apiHandler.Catalog(fakeResponseWriter, request)
+
+			Expect(fakeResponseWriter.WriteHeaderArgsForCall(0)).To(Equal(http.StatusForbidden))
+			Expect(fakeServiceBroker.ServicesCallCount()).To(BeZero())
+		})
+	})
 })
 
 func newServicesRequest() *http.Request {
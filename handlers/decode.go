@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
+)
+
+const (
+	defaultMaxBodyBytes = 1 << 20
+
+	requestBodyTooLargeKey = "request-body-too-large"
+)
+
+// WithStrictDecoding rejects request bodies that are larger than maxBodyBytes (default 1MiB), that have
+// unknown top-level fields or data after the JSON object, or whose "parameters" or "context" are not
+// JSON objects. Errors report the line and column, or the field, where the problem was found.
+func WithStrictDecoding(maxBodyBytes int64) Option {
+	return func(h *APIHandler) {
+		if maxBodyBytes <= 0 {
+			maxBodyBytes = defaultMaxBodyBytes
+		}
+		h.maxBodyBytes = maxBodyBytes
+	}
+}
+
+// decodeDetails decodes the request body into details, which must be a pointer to a struct
+func (h APIHandler) decodeDetails(w http.ResponseWriter, req *http.Request, details any) error {
+	if h.maxBodyBytes == 0 {
+		return json.NewDecoder(req.Body).Decode(details)
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, h.maxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return apiresponses.NewFailureResponseBuilder(
+			fmt.Errorf("request body must not be larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge, requestBodyTooLargeKey,
+		).Build()
+	case err != nil:
+		return err
+	}
+
+	fields, err := topLevelFields(data)
+	if err != nil {
+		return err
+	}
+
+	allowed := domain.GetJsonNames(reflect.ValueOf(details).Elem())
+	for _, f := range fields {
+		if !slices.Contains(allowed, f.name) {
+			return fmt.Errorf("unknown field %q at %s", f.name, location(data, f.nameOffset))
+		}
+	}
+
+	for _, f := range fields {
+		if (f.name == "parameters" || f.name == "context") && !bytes.HasPrefix(f.value, []byte("{")) {
+			var rawParamsInvalid *apiresponses.FailureResponse
+			errors.As(apiresponses.ErrRawParamsInvalid, &rawParamsInvalid)
+			return rawParamsInvalid.AppendErrorMessage(fmt.Sprintf("(%q at %s must be a JSON object)", f.name, location(data, f.valueOffset)))
+		}
+	}
+
+	if err := json.Unmarshal(data, details); err != nil {
+		return locateError(data, err)
+	}
+	return nil
+}
+
+// respondDecodeError responds with a FailureResponse from decodeDetails(), or with 422 Unprocessable Entity
+func (h APIHandler) respondDecodeError(w http.ResponseWriter, req *http.Request, logger blog.Blog, requestId, logKey string, err error) {
+	var failure *apiresponses.FailureResponse
+	if errors.As(err, &failure) {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
+	logger.Error(logKey, err)
+	h.respond(w, http.StatusUnprocessableEntity, requestId, apiresponses.ErrorResponse{
+		Description: err.Error(),
+	})
+}
+
+type field struct {
+	name        string
+	nameOffset  int64
+	valueOffset int64
+	value       json.RawMessage
+}
+
+// topLevelFields splits a JSON object into its fields, recording the offsets of each name and value. It fails if
+// the data is not a single JSON object.
+func topLevelFields(data []byte) ([]field, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, locateError(data, err)
+	} else if token != json.Delim('{') {
+		return nil, fmt.Errorf("request body must be a JSON object at %s", location(data, nextToken(data, 0)))
+	}
+
+	var fields []field
+	for decoder.More() {
+		// the decoder consumes separators lazily, so skip them to find the start of the name and value
+		nameOffset := skipSeparator(data, decoder.InputOffset(), ',')
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, locateError(data, err)
+		}
+		name, _ := token.(string)
+		valueOffset := skipSeparator(data, decoder.InputOffset(), ':')
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, locateError(data, err)
+		}
+		fields = append(fields, field{name: name, nameOffset: nameOffset, valueOffset: valueOffset, value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, locateError(data, err)
+	}
+
+	if offset := nextToken(data, decoder.InputOffset()); offset < int64(len(data)) {
+		return nil, fmt.Errorf("unexpected data after the JSON object at %s", location(data, offset))
+	}
+	return fields, nil
+}
+
+// locateError adds the location in the request body to JSON syntax and type errors
+func locateError(data []byte, err error) error {
+	// the offsets of json errors count the bytes read, so the byte in error is the one before the offset
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("invalid JSON at %s: %s", location(data, syntaxErr.Offset-1), syntaxErr)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("field %q at %s must be %s, but is a %s", typeErr.Field, location(data, typeErr.Offset-1), typeErr.Type, typeErr.Value)
+	case errors.Is(err, io.EOF):
+		return errors.New("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("invalid JSON at %s: unexpected end of input", location(data, int64(len(data))))
+	default:
+		return err
+	}
+}
+
+// nextToken returns the offset of the first non-whitespace byte at or after offset
+func nextToken(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n"), data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// skipSeparator returns the offset of the next token after offset, skipping the separator if there is one
+func skipSeparator(data []byte, offset int64, separator byte) int64 {
+	offset = nextToken(data, offset)
+	if offset < int64(len(data)) && data[offset] == separator {
+		offset = nextToken(data, offset+1)
+	}
+	return offset
+}
+
+// location converts the index of a byte in the request body into a line and column, counting from 1
+func location(data []byte, index int64) string {
+	index = min(max(index, 0), int64(len(data)))
+	before := data[:index]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := index - int64(bytes.LastIndexByte(before, '\n'))
+	return fmt.Sprintf("line %d, column %d", line, column)
+}
@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+)
+
+var _ = Describe("Strict decoding", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		opts              []handlers.Option
+	)
+
+	provision := func(body string) *httptest.ResponseRecorder {
+		apiHandler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), opts...)
+		return serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance", body)
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ServicesReturns([]domain.Service{{
+			ID:       "a-service",
+			Bindable: true,
+			Plans:    []domain.ServicePlan{{ID: "a-plan"}},
+		}}, nil)
+		opts = []handlers.Option{handlers.WithStrictDecoding(0)}
+	})
+
+	It("accepts a valid request", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","parameters":{"a":1},"context":{"platform":"cloudfoundry"}} ` + "\n")
+		Expect(response.Code).To(Equal(http.StatusCreated))
+
+		_, _, details, _ := fakeServiceBroker.ProvisionArgsForCall(0)
+		Expect(details.RawParameters).To(MatchJSON(`{"a":1}`))
+	})
+
+	It("rejects unknown fields", func() {
+		response := provision(`{"service_id":"a-service",` + "\n" + ` "plan_id":"a-plan", "colour":"blue"}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"unknown field \"colour\" at line 2, column 22"}`))
+		Expect(fakeServiceBroker.ProvisionCallCount()).To(BeZero())
+	})
+
+	It("rejects data after the JSON object", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan"} {}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"unexpected data after the JSON object at line 1, column 47"}`))
+	})
+
+	It("rejects a body that is not a JSON object", func() {
+		response := provision(` ["a-service"]`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"request body must be a JSON object at line 1, column 2"}`))
+	})
+
+	It("reports the location of invalid JSON", func() {
+		response := provision("{\n  \"service_id\": \"a-service\",\n  \"plan_id\" \"a-plan\"\n}")
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"invalid JSON at line 3, column 13: invalid character '\"' after object key"}`))
+	})
+
+	It("reports the location of a field with the wrong type", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","organization_guid":42}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"field \"organization_guid\" at line 1, column 67 must be string, but is a number"}`))
+	})
+
+	It("rejects parameters that are not an object", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","parameters":"a=1"}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"The format of the parameters is not valid JSON (\"parameters\" at line 1, column 59 must be a JSON object)"}`))
+	})
+
+	It("rejects a context that is not an object", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","context":null}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(ContainSubstring(`\"context\" at line 1, column 56 must be a JSON object`))
+	})
+
+	It("rejects a body that is too large", func() {
+		opts = []handlers.Option{handlers.WithStrictDecoding(32)}
+
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"request body must not be larger than 32 bytes"}`))
+	})
+
+	It("rejects an empty body", func() {
+		response := provision(``)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"request body is empty"}`))
+	})
+
+	It("applies to update and bind requests", func() {
+		apiHandler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), opts...)
+
+		response := serve(apiHandler.Update, http.MethodPatch, "/v2/service_instances/an-instance", `{"service_id":"a-service","colour":"blue"}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(ContainSubstring(`unknown field \"colour\"`))
+
+		response = serve(apiHandler.Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding", `{"service_id":"a-service","plan_id":"a-plan","parameters":[]}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(ContainSubstring(`\"parameters\" at line 1, column 59 must be a JSON object`))
+	})
+
+	When("strict decoding is not enabled", func() {
+		BeforeEach(func() {
+			opts = nil
+		})
+
+		It("ignores unknown fields and trailing data", func() {
+			Expect(provision(`{"service_id":"a-service","plan_id":"a-plan","colour":"blue"} {}`).Code).To(Equal(http.StatusCreated))
+		})
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
@@ -42,14 +43,29 @@ func (h APIHandler) Deprovision(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if !h.checkScope(w, req, logger, requestId, auth.Deprovision, details.ServiceID, details.PlanID) {
+		return
+	}
+
 	asyncAllowed := req.FormValue("accepts_incomplete") == "true"
 
+	req, plan, ok := h.resolveCatalog(w, req, logger, requestId, details.ServiceID, details.PlanID)
+	if !ok {
+		return
+	}
+
+	if failure := checkAsyncRequired(plan, domain.AsyncDeprovision, asyncAllowed); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
 	deprovisionSpec, err := h.serviceBroker.Deprovision(req.Context(), instanceID, details, asyncAllowed)
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
@@ -59,7 +75,19 @@ func (h APIHandler) Deprovision(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if deprovisionSpec.Job != nil {
+		operationData, err := h.startJob(domain.AsyncDeprovision, instanceID, "", deprovisionSpec.Job, asyncAllowed)
+		if err != nil {
+			h.respondError(w, req, logger, requestId, err)
+			return
+		}
+		deprovisionSpec.IsAsync = true
+		deprovisionSpec.OperationData = operationData
+	}
+
 	if deprovisionSpec.IsAsync {
+		h.pollingDeadlines.start(plan, domain.AsyncDeprovision, instanceID, "", deprovisionSpec.OperationData)
+		setRetryAfter(w, h.retryAfter(deprovisionSpec.RetryAfter, 0))
 		h.respond(w, http.StatusAccepted, requestId, apiresponses.DeprovisionResponse{OperationData: deprovisionSpec.OperationData})
 	} else {
 		h.respond(w, http.StatusOK, requestId, apiresponses.EmptyResponse{})
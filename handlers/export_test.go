@@ -0,0 +1,10 @@
+package handlers
+
+import "time"
+
+// WithClock replaces the clock that the handler uses to time asynchronous operations
+func WithClock(now func() time.Time) Option {
+	return func(h *APIHandler) {
+		h.pollingDeadlines.now = now
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
@@ -37,12 +38,16 @@ func (h APIHandler) GetBinding(w http.ResponseWriter, req *http.Request) {
 		PlanID:    req.URL.Query().Get("plan_id"),
 	}
 
+	if !h.checkScope(w, req, logger, requestId, auth.GetBinding, details.ServiceID, details.PlanID) {
+		return
+	}
+
 	binding, err := h.serviceBroker.GetBinding(req.Context(), instanceID, bindingID, details)
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
@@ -8,6 +8,7 @@ import (
 
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
@@ -37,12 +38,21 @@ func (h APIHandler) GetInstance(w http.ResponseWriter, req *http.Request) {
 		PlanID:    req.URL.Query().Get("plan_id"),
 	}
 
+	if !h.checkScope(w, req, logger, requestId, auth.GetInstance, details.ServiceID, details.PlanID) {
+		return
+	}
+
+	req, _, ok := h.resolveCatalog(w, req, logger, requestId, details.ServiceID, details.PlanID)
+	if !ok {
+		return
+	}
+
 	instanceDetails, err := h.serviceBroker.GetInstance(req.Context(), instanceID, details)
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
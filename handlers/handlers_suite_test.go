@@ -1,6 +1,9 @@
 package handlers_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -14,3 +17,29 @@ func TestHandlers(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Handlers Suite")
 }
+
+// serve calls the handler with a request for the target using API version 2.17, and returns the response
+func serve(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	return serveVersion("2.17", handler, method, target, body)
+}
+
+// serveVersion calls the handler with a request for the target using the API version, and returns the response.
+// The instance_id and binding_id path values are set from the target, as they would be by the router.
+func serveVersion(apiVersion string, handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("X-Broker-API-Version", apiVersion)
+
+	segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		switch segments[i] {
+		case "service_instances":
+			request.SetPathValue("instance_id", segments[i+1])
+		case "service_bindings":
+			request.SetPathValue("binding_id", segments[i+1])
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	return recorder
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+// supportsInstanceUsable reports whether the API version has the instance_usable and update_repeatable
+// fields, which were added in 2.15
+func (v brokerVersion) supportsInstanceUsable() bool {
+	return v.Major > 2 || (v.Major == 2 && v.Minor >= 15)
+}
+
+// failureResponse returns the body of the response for a FailureResponse, without the fields that the API
+// version does not have
+func failureResponse(failure *apiresponses.FailureResponse, version brokerVersion) any {
+	response := failure.ErrorResponse()
+	if errorResponse, ok := response.(apiresponses.ErrorResponse); ok && !version.supportsInstanceUsable() {
+		errorResponse.InstanceUsable = nil
+		errorResponse.UpdateRepeatable = nil
+		return errorResponse
+	}
+	return response
+}
+
+// lastOperationResponse returns the body of the response for the state of an operation on an instance,
+// without the fields that the API version does not have
+func lastOperationResponse(lastOperation domain.LastOperation, version brokerVersion) apiresponses.LastOperationResponse {
+	response := apiresponses.LastOperationResponse{
+		State:       lastOperation.State,
+		Description: lastOperation.Description,
+	}
+	if version.supportsInstanceUsable() {
+		response.InstanceUsable = lastOperation.InstanceUsable
+		response.UpdateRepeatable = lastOperation.UpdateRepeatable
+	}
+	return response
+}
@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+)
+
+var _ = Describe("instance_usable and update_repeatable", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		failure           error
+	)
+
+	newHandler := func() handlers.APIHandler {
+		return handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)))
+	}
+
+	update := func(apiVersion string) *httptest.ResponseRecorder {
+		return serveVersion(apiVersion, newHandler().Update, http.MethodPatch, "/v2/service_instances/an-instance", `{"service_id":"a-service","plan_id":"a-plan","previous_values":{"plan_id":"a-plan"}}`)
+	}
+
+	lastOperation := func(apiVersion string) *httptest.ResponseRecorder {
+		return serveVersion(apiVersion, newHandler().LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation", "")
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Bindable: true, PlanUpdatable: true, Plans: []domain.ServicePlan{{ID: "a-plan"}}}}, nil)
+
+		failure = apiresponses.NewFailureResponseBuilder(errors.New("disk is full"), http.StatusUnprocessableEntity, "update-failed").
+			WithInstanceUsable(true).
+			WithUpdateRepeatable(false).
+			Build()
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{}, failure)
+		fakeServiceBroker.UpdateReturns(domain.UpdateServiceSpec{}, failure)
+		fakeServiceBroker.DeprovisionReturns(domain.DeprovisionServiceSpec{}, failure)
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{
+			State:            domain.Failed,
+			Description:      "disk is full",
+			InstanceUsable:   domain.InstanceUsableValue(true),
+			UpdateRepeatable: domain.UpdateRepeatableValue(false),
+		}, nil)
+	})
+
+	It("sends them in error responses for API version 2.15 and later", func() {
+		response := update("2.15")
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"disk is full","instance_usable":true,"update_repeatable":false}`))
+
+		response = serve(newHandler().Deprovision, http.MethodDelete, "/v2/service_instances/an-instance?service_id=a-service&plan_id=a-plan", "")
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"disk is full","instance_usable":true,"update_repeatable":false}`))
+	})
+
+	It("does not send them in error responses for earlier API versions", func() {
+		response := update("2.14")
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"disk is full"}`))
+	})
+
+	It("filters them from provision error responses by API version", func() {
+		provision := func(apiVersion string) *httptest.ResponseRecorder {
+			return serveVersion(apiVersion, newHandler().Provision, http.MethodPut, "/v2/service_instances/an-instance", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		}
+
+		response := provision("2.14")
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"disk is full"}`))
+
+		Expect(provision("2.15").Body.String()).To(MatchJSON(`{"description":"disk is full","instance_usable":true,"update_repeatable":false}`))
+	})
+
+	It("filters them from the error responses of every endpoint by API version", func() {
+		fakeServiceBroker.BindReturns(domain.Binding{}, failure)
+		fakeServiceBroker.UnbindReturns(domain.UnbindSpec{}, failure)
+		fakeServiceBroker.GetInstanceReturns(domain.GetInstanceDetailsSpec{}, failure)
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{}, failure)
+
+		endpoints := map[string]func(apiVersion string) *httptest.ResponseRecorder{
+			"bind": func(apiVersion string) *httptest.ResponseRecorder {
+				return serveVersion(apiVersion, newHandler().Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding", `{"service_id":"a-service","plan_id":"a-plan"}`)
+			},
+			"unbind": func(apiVersion string) *httptest.ResponseRecorder {
+				return serveVersion(apiVersion, newHandler().Unbind, http.MethodDelete, "/v2/service_instances/an-instance/service_bindings/a-binding?service_id=a-service&plan_id=a-plan", "")
+			},
+			"get instance": func(apiVersion string) *httptest.ResponseRecorder {
+				return serveVersion(apiVersion, newHandler().GetInstance, http.MethodGet, "/v2/service_instances/an-instance", "")
+			},
+			"last operation": lastOperation,
+		}
+
+		for name, endpoint := range endpoints {
+			response := endpoint("2.14")
+			Expect(response.Code).To(Equal(http.StatusUnprocessableEntity), name)
+			Expect(response.Body.String()).To(MatchJSON(`{"description":"disk is full"}`), name)
+
+			Expect(endpoint("2.15").Body.String()).To(MatchJSON(`{"description":"disk is full","instance_usable":true,"update_repeatable":false}`), name)
+		}
+	})
+
+	It("sends them in last operation responses for API version 2.15 and later", func() {
+		Expect(lastOperation("2.15").Body.String()).To(MatchJSON(`{"state":"failed","description":"disk is full","instance_usable":true,"update_repeatable":false}`))
+	})
+
+	It("does not send them in last operation responses for earlier API versions", func() {
+		Expect(lastOperation("2.14").Body.String()).To(MatchJSON(`{"state":"failed","description":"disk is full"}`))
+	})
+})
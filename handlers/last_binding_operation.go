@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
@@ -37,20 +38,21 @@ func (h APIHandler) LastBindingOperation(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	if !h.checkScope(w, req, logger, requestId, auth.LastBindingOperation, pollDetails.ServiceID, pollDetails.PlanID) {
+		return
+	}
+
 	logger.Info("starting-check-for-binding-operation")
 
-	lastOperation, err := h.serviceBroker.LastBindingOperation(req.Context(), instanceID, bindingID, pollDetails)
+	var plan *domain.ServicePlan
+	if pollDetails.PlanID != "" {
+		_, plan = h.findInCatalog(req.Context(), pollDetails.ServiceID, pollDetails.PlanID)
+	}
+	lastOperation, err := h.lastOperation(req.Context(), logger, plan, instanceID, bindingID, pollDetails.OperationData, func() (domain.LastOperation, error) {
+		return h.serviceBroker.LastBindingOperation(req.Context(), instanceID, bindingID, pollDetails)
+	})
 	if err != nil {
-		switch err := err.(type) {
-		case *apiresponses.FailureResponse:
-			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
-		default:
-			logger.Error(unknownErrorKey, err)
-			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
-				Description: err.Error(),
-			})
-		}
+		h.respondError(w, req, logger, requestId, err)
 		return
 	}
 
@@ -60,5 +62,6 @@ func (h APIHandler) LastBindingOperation(w http.ResponseWriter, req *http.Reques
 		State:       lastOperation.State,
 		Description: lastOperation.Description,
 	}
+	setRetryAfter(w, lastOperation.RetryAfter)
 	h.respond(w, http.StatusOK, requestId, lastOperationResponse)
 }
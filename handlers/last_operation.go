@@ -5,8 +5,8 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
-	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
 )
@@ -27,27 +27,25 @@ func (h APIHandler) LastOperation(w http.ResponseWriter, req *http.Request) {
 
 	requestId := fmt.Sprintf("%v", req.Context().Value(middlewares.RequestIdentityKey))
 
-	lastOperation, err := h.serviceBroker.LastOperation(req.Context(), instanceID, pollDetails)
-	if err != nil {
-		switch err := err.(type) {
-		case *apiresponses.FailureResponse:
-			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
-		default:
-			logger.Error(unknownErrorKey, err)
-			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
-				Description: err.Error(),
-			})
-		}
+	if !h.checkScope(w, req, logger, requestId, auth.LastOperation, pollDetails.ServiceID, pollDetails.PlanID) {
 		return
 	}
 
-	logger.Info("done-check-for-operation", slog.Any("state", lastOperation.State))
+	req, plan, ok := h.resolveCatalog(w, req, logger, requestId, pollDetails.ServiceID, pollDetails.PlanID)
+	if !ok {
+		return
+	}
 
-	lastOperationResponse := apiresponses.LastOperationResponse{
-		State:       lastOperation.State,
-		Description: lastOperation.Description,
+	lastOperation, err := h.lastOperation(req.Context(), logger, plan, instanceID, "", pollDetails.OperationData, func() (domain.LastOperation, error) {
+		return h.serviceBroker.LastOperation(req.Context(), instanceID, pollDetails)
+	})
+	if err != nil {
+		h.respondError(w, req, logger, requestId, err)
+		return
 	}
 
-	h.respond(w, http.StatusOK, requestId, lastOperationResponse)
+	logger.Info("done-check-for-operation", slog.Any("state", lastOperation.State))
+
+	setRetryAfter(w, lastOperation.RetryAfter)
+	h.respond(w, http.StatusOK, requestId, lastOperationResponse(lastOperation, getAPIVersion(req)))
 }
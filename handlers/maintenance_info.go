@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+// checkMaintenanceInfo compares the maintenance_info of a request with the catalog plan. Only the version
+// is compared when the catalog specifies one, as the Open Service Broker API requires. Otherwise the whole
+// maintenance_info must match.
+func (h APIHandler) checkMaintenanceInfo(requested *domain.MaintenanceInfo, plan *domain.ServicePlan) *apiresponses.FailureResponse {
+	if h.skipMaintenanceInfoCheck || requested == nil || plan == nil {
+		return nil
+	}
+
+	if plan.MaintenanceInfo == nil {
+		return apiresponses.ErrMaintenanceInfoNilConflict
+	}
+
+	if plan.MaintenanceInfo.Version != "" {
+		if plan.MaintenanceInfo.Version != requested.Version {
+			return apiresponses.ErrMaintenanceInfoConflict
+		}
+		return nil
+	}
+
+	if !plan.MaintenanceInfo.Equals(*requested) {
+		return apiresponses.ErrMaintenanceInfoConflict
+	}
+	return nil
+}
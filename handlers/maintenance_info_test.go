@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+)
+
+var _ = Describe("maintenance_info", func() {
+	const (
+		serviceID = "a-service"
+		planID    = "a-plan"
+	)
+
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		plan              domain.ServicePlan
+		options           []handlers.Option
+	)
+
+	newHandler := func() handlers.APIHandler {
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: serviceID, PlanUpdatable: true, Plans: []domain.ServicePlan{plan}}}, nil)
+		return handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), options...)
+	}
+
+	provision := func(maintenanceInfo string) *httptest.ResponseRecorder {
+		return serve(newHandler().Provision, http.MethodPut, "/v2/service_instances/an-instance", `{"service_id":"a-service","plan_id":"a-plan","maintenance_info":`+maintenanceInfo+`}`)
+	}
+
+	update := func(maintenanceInfo string) *httptest.ResponseRecorder {
+		return serve(newHandler().Update, http.MethodPatch, "/v2/service_instances/an-instance", `{"service_id":"a-service","previous_values":{"plan_id":"a-plan"},"maintenance_info":`+maintenanceInfo+`}`)
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		plan = domain.ServicePlan{ID: planID, MaintenanceInfo: &domain.MaintenanceInfo{Version: "1.2.3", Description: "latest"}}
+		options = nil
+	})
+
+	It("accepts a version that matches the catalog", func() {
+		Expect(provision(`{"version":"1.2.3"}`).Code).To(Equal(http.StatusCreated))
+		Expect(update(`{"version":"1.2.3"}`).Code).To(Equal(http.StatusOK))
+	})
+
+	It("accepts a request without maintenance_info", func() {
+		Expect(provision(`null`).Code).To(Equal(http.StatusCreated))
+		Expect(update(`null`).Code).To(Equal(http.StatusOK))
+	})
+
+	It("rejects a version that does not match the catalog", func() {
+		for _, response := range []*httptest.ResponseRecorder{provision(`{"version":"1.2.2"}`), update(`{"version":"1.2.2"}`)} {
+			Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(response.Body.String()).To(MatchJSON(`{
+				"error": "MaintenanceInfoConflict",
+				"description": "passed maintenance_info does not match the catalog maintenance_info"
+			}`))
+		}
+		Expect(fakeServiceBroker.ProvisionCallCount()).To(BeZero())
+		Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+	})
+
+	It("rejects maintenance_info when the catalog has none", func() {
+		plan.MaintenanceInfo = nil
+
+		response := update(`{"version":"1.2.3"}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{
+			"error": "MaintenanceInfoConflict",
+			"description": "maintenance_info was passed, but the broker catalog contains no maintenance_info"
+		}`))
+	})
+
+	It("compares the whole maintenance_info when the catalog has no version", func() {
+		plan.MaintenanceInfo = &domain.MaintenanceInfo{Public: map[string]string{"edition": "standard"}}
+
+		Expect(provision(`{"public":{"edition":"standard"}}`).Code).To(Equal(http.StatusCreated))
+		Expect(provision(`{"public":{"edition":"enterprise"}}`).Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("checks the new plan when the plan is changing", func() {
+		response := serve(newHandler().Update, http.MethodPatch, "/v2/service_instances/an-instance", `{"service_id":"a-service","plan_id":"a-plan","previous_values":{"plan_id":"old-plan"},"maintenance_info":{"version":"1.0.0"}}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(ContainSubstring("MaintenanceInfoConflict"))
+	})
+
+	When("the check is disabled", func() {
+		BeforeEach(func() {
+			options = []handlers.Option{handlers.WithoutMaintenanceInfoCheck()}
+		})
+
+		It("leaves the check to the broker", func() {
+			Expect(provision(`{"version":"1.2.2"}`).Code).To(Equal(http.StatusCreated))
+			Expect(update(`{"version":"1.2.2"}`).Code).To(Equal(http.StatusOK))
+			Expect(fakeServiceBroker.ProvisionCallCount()).To(Equal(1))
+			Expect(fakeServiceBroker.UpdateCallCount()).To(Equal(1))
+		})
+	})
+})
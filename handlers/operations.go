@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+var noOperationEngineError = errors.New("the service broker returned an operation job, but no operation engine is configured")
+
+// WithOperationEngine runs the OperationJob returned by the ServiceBroker from Provision, Update, Deprovision,
+// Bind and Unbind on the engine, and answers last operation requests for these operations from the engine
+func WithOperationEngine(engine *operations.Engine) Option {
+	return func(h *APIHandler) {
+		h.operations = engine
+	}
+}
+
+// startJob starts the job of an asynchronous operation, and returns the operation data for the platform.
+// The job is not started unless the platform accepts incomplete responses.
+func (h APIHandler) startJob(operationType domain.AsyncOperation, instanceID, bindingID string, job domain.OperationJob, asyncAllowed bool) (string, error) {
+	switch {
+	case h.operations == nil:
+		return "", noOperationEngineError
+	case !asyncAllowed:
+		return "", apiresponses.ErrAsyncRequired
+	}
+
+	operation, err := h.operations.Start(operationType, instanceID, bindingID, job)
+	if err != nil {
+		return "", err
+	}
+	return operation.ID, nil
+}
+
+// engineOperation returns an operation run by the operation engine, or false if the operation is not
+// known to the engine and the ServiceBroker should be asked
+func (h APIHandler) engineOperation(instanceID, bindingID, operationData string) (operations.Operation, bool, error) {
+	if h.operations == nil || operationData == "" {
+		return operations.Operation{}, false, nil
+	}
+	return h.operations.Lookup(instanceID, bindingID, operationData)
+}
+
+// engineLastOperation returns the state of an operation run by the operation engine
+func (h APIHandler) engineLastOperation(operation operations.Operation) domain.LastOperation {
+	lastOperation := operation.LastOperation()
+	if lastOperation.State == domain.InProgress {
+		lastOperation.RetryAfter = h.retryAfter(0, h.pollingDeadlines.now().Sub(operation.StartedAt))
+	}
+	return lastOperation
+}
+
+// respondError responds with a FailureResponse, or with 500 Internal Server Error for other errors
+func (h APIHandler) respondError(w http.ResponseWriter, req *http.Request, logger blog.Blog, requestId string, err error) {
+	switch err := err.(type) {
+	case *apiresponses.FailureResponse:
+		logger.Error(err.LoggerAction(), err)
+		h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
+	default:
+		logger.Error(unknownErrorKey, err)
+		h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+var _ = Describe("Operation engine", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		engine            *operations.Engine
+		apiHandler        handlers.APIHandler
+		release           chan error
+	)
+
+	job := func(ctx context.Context, progress func(string)) error {
+		progress("creating database")
+		return <-release
+	}
+
+	operationData := func(response *httptest.ResponseRecorder) string {
+		var body struct {
+			Operation string `json:"operation"`
+		}
+		Expect(json.Unmarshal(response.Body.Bytes(), &body)).To(Succeed())
+		return body.Operation
+	}
+
+	lastOperation := func(operation string) func() string {
+		return func() string {
+			return serve(apiHandler.LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?operation="+operation, "").Body.String()
+		}
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Bindable: true, Plans: []domain.ServicePlan{{ID: "a-plan"}}}}, nil)
+		var err error
+		engine, err = operations.NewEngine(operations.Config{Logger: slog.New(slog.NewJSONHandler(GinkgoWriter, nil))})
+		Expect(err).NotTo(HaveOccurred())
+		apiHandler = handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), handlers.WithOperationEngine(engine))
+		release = make(chan error, 1)
+	})
+
+	AfterEach(func() {
+		Expect(engine.Shutdown(context.Background())).To(Succeed())
+	})
+
+	It("runs the job returned by Provision and answers last_operation", func() {
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{Job: job}, nil)
+
+		response := serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+		operation := operationData(response)
+		Expect(operation).NotTo(BeEmpty())
+
+		Eventually(lastOperation(operation)).Should(MatchJSON(`{"state":"in progress","description":"creating database"}`))
+
+		release <- nil
+		Eventually(lastOperation(operation)).Should(MatchJSON(`{"state":"succeeded","description":"creating database"}`))
+		Expect(fakeServiceBroker.LastOperationCallCount()).To(BeZero())
+	})
+
+	It("reports a failed job", func() {
+		fakeServiceBroker.UpdateReturns(domain.UpdateServiceSpec{Job: job}, nil)
+		release <- errors.New("out of disk")
+
+		response := serve(apiHandler.Update, http.MethodPatch, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan","previous_values":{"plan_id":"a-plan"}}`)
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+
+		Eventually(lastOperation(operationData(response))).Should(MatchJSON(`{"state":"failed","description":"out of disk"}`))
+	})
+
+	It("runs the jobs of deprovision, bind and unbind", func() {
+		close(release)
+		fakeServiceBroker.DeprovisionReturns(domain.DeprovisionServiceSpec{Job: job}, nil)
+		fakeServiceBroker.BindReturns(domain.Binding{Job: job}, nil)
+		fakeServiceBroker.UnbindReturns(domain.UnbindSpec{Job: job}, nil)
+
+		response := serve(apiHandler.Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+		bindOperation := operationData(response)
+		Eventually(func() string {
+			return serve(apiHandler.LastBindingOperation, http.MethodGet, "/v2/service_instances/an-instance/service_bindings/a-binding/last_operation?operation="+bindOperation, "").Body.String()
+		}).Should(MatchJSON(`{"state":"succeeded","description":"creating database"}`))
+		Expect(fakeServiceBroker.LastBindingOperationCallCount()).To(BeZero())
+
+		response = serve(apiHandler.Unbind, http.MethodDelete, "/v2/service_instances/an-instance/service_bindings/a-binding?accepts_incomplete=true&service_id=a-service&plan_id=a-plan", "")
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+		Expect(operationData(response)).NotTo(BeEmpty())
+
+		Eventually(func() int {
+			return serve(apiHandler.Deprovision, http.MethodDelete, "/v2/service_instances/an-instance?accepts_incomplete=true&service_id=a-service&plan_id=a-plan", "").Code
+		}).Should(Equal(http.StatusAccepted))
+	})
+
+	It("rejects a job when the platform does not accept incomplete responses", func() {
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{Job: job}, nil)
+
+		response := serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(ContainSubstring("AsyncRequired"))
+	})
+
+	It("rejects an operation while another is in progress", func() {
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{Job: job}, nil)
+		fakeServiceBroker.UpdateReturns(domain.UpdateServiceSpec{Job: job}, nil)
+
+		response := serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+
+		response = serve(apiHandler.Update, http.MethodPatch, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan","previous_values":{"plan_id":"a-plan"}}`)
+		Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(response.Body.String()).To(MatchJSON(`{"error":"ConcurrencyError","description":"another operation for this service instance is in progress"}`))
+		release <- nil
+	})
+
+	It("asks the broker about operations that the engine does not know", func() {
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.Succeeded}, nil)
+
+		Expect(lastOperation("broker-operation")()).To(MatchJSON(`{"state":"succeeded"}`))
+		_, _, details := fakeServiceBroker.LastOperationArgsForCall(0)
+		Expect(details.OperationData).To(Equal("broker-operation"))
+	})
+
+	It("fails when the broker returns a job but there is no engine", func() {
+		apiHandler = handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)))
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{Job: job}, nil)
+
+		response := serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusInternalServerError))
+	})
+})
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
+	"github.com/pivotal-cf/brokerapi/v12/internal/jsonschema"
+)
+
+const (
+	invalidParametersErrorKey = "invalid-parameters"
+	invalidSchemaErrorKey     = "invalid-schema"
+
+	// maxCompiledSchemas bounds the number of compiled schemas that are kept. Schemas of plans that were
+	// removed from the catalog are not removed individually, so the cache is emptied when it is full.
+	maxCompiledSchemas = 1000
+
+	// maxParametersBytes limits the size of the parameters that are validated
+	maxParametersBytes = 256 << 10
+)
+
+var (
+	invalidParametersError  = errors.New("parameters do not conform to the plan schema")
+	parametersTooLargeError = fmt.Errorf("parameters must be at most %d bytes to be validated", maxParametersBytes)
+)
+
+// checkParameters validates parameters against a schema of the plan from the catalog when parameter validation
+// is enabled. The operation is the log key of the request, and identifies the schema of the plan. If the
+// parameters are not valid, it responds with an error and returns false. Absent parameters are validated as
+// an empty object if absentAsEmpty is set, and are otherwise not validated.
+func (h APIHandler) checkParameters(w http.ResponseWriter, logger blog.Blog, requestId, operation, planID string, schema domain.Schema, parameters json.RawMessage, absentAsEmpty bool) bool {
+	if !h.validateParameters || len(schema.Parameters) == 0 {
+		return true
+	}
+
+	if len(parameters) == 0 {
+		if !absentAsEmpty {
+			return true
+		}
+		parameters = json.RawMessage("{}")
+	}
+
+	if len(parameters) > maxParametersBytes {
+		logger.Error(invalidParametersErrorKey, parametersTooLargeError)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: parametersTooLargeError.Error(),
+		})
+		return false
+	}
+
+	compiled, err := h.schemas.compile(schemaKey{operation: operation, planID: planID}, schema.Parameters)
+	if err != nil {
+		logger.Error(invalidSchemaErrorKey, err)
+		h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+		return false
+	}
+
+	violations, err := compiled.Validate(parameters)
+	if err != nil {
+		logger.Error(invalidParametersErrorKey, err)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+		return false
+	}
+
+	if len(violations) == 0 {
+		return true
+	}
+
+	response := apiresponses.ParameterValidationErrorResponse{Description: invalidParametersError.Error()}
+	for _, v := range violations {
+		response.Violations = append(response.Violations, apiresponses.ParameterViolation{
+			Path:    v.Path,
+			Keyword: v.Keyword,
+			Message: v.Message,
+		})
+	}
+
+	logger.Error(invalidParametersErrorKey, invalidParametersError, slog.Any("violations", response.Violations))
+	h.respond(w, http.StatusBadRequest, requestId, response)
+	return false
+}
+
+type schemaKey struct {
+	operation string
+	planID    string
+}
+
+type compiledSchema struct {
+	source   map[string]any
+	compiled *jsonschema.Schema
+}
+
+// compiledSchemas caches the compiled schema of each plan and operation. A schema is compiled again when the
+// catalog returns a schema that differs from the one that was compiled.
+type compiledSchemas struct {
+	lock    sync.Mutex
+	schemas map[schemaKey]compiledSchema
+}
+
+func newCompiledSchemas() *compiledSchemas {
+	return &compiledSchemas{schemas: make(map[schemaKey]compiledSchema)}
+}
+
+func (c *compiledSchemas) compile(key schemaKey, schema map[string]any) (*jsonschema.Schema, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if cached, ok := c.schemas[key]; ok && sameSchema(cached.source, schema) {
+		return cached.compiled, nil
+	}
+
+	compiled, err := jsonschema.Compile(schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.schemas) >= maxCompiledSchemas {
+		clear(c.schemas)
+	}
+	c.schemas[key] = compiledSchema{source: schema, compiled: compiled}
+	return compiled, nil
+}
+
+// sameSchema reports whether a schema from the catalog is the one that was compiled. Brokers that return the same
+// catalog each time are recognised without comparing the contents, and brokers that build a new catalog for each
+// request are recognised by comparing the contents.
+func sameSchema(compiled, schema map[string]any) bool {
+	return reflect.ValueOf(compiled).UnsafePointer() == reflect.ValueOf(schema).UnsafePointer() || reflect.DeepEqual(compiled, schema)
+}
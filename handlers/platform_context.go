@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+)
+
+const platformNotAllowedKey = "platform-not-allowed"
+
+// WithAllowedPlatforms rejects provision, update and bind requests with 400 Bad Request if the
+// platform in their context is not one of those listed, or if the context cannot be decoded.
+// Requests without a context are accepted.
+func WithAllowedPlatforms(platforms ...string) Option {
+	return func(h *APIHandler) {
+		h.allowedPlatforms = append(h.allowedPlatforms, platforms...)
+	}
+}
+
+// checkPlatform returns an error if there is an allowlist of platforms and the context of the
+// request is invalid, or is from a platform that is not allowed
+func (h APIHandler) checkPlatform(details interface {
+	GetPlatformContext() (domain.PlatformContext, error)
+}) error {
+	if h.allowedPlatforms == nil {
+		return nil
+	}
+
+	platformContext, err := details.GetPlatformContext()
+	switch {
+	case err != nil:
+		return err
+	case platformContext == nil:
+		return nil
+	case !slices.Contains(h.allowedPlatforms, platformContext.GetPlatform()):
+		return fmt.Errorf("platform %q is not allowed", platformContext.GetPlatform())
+	default:
+		return nil
+	}
+}
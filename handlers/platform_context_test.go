@@ -0,0 +1,61 @@
+package handlers_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+)
+
+var _ = Describe("Allowed platforms", func() {
+	var fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+
+	provision := func(body string) *httptest.ResponseRecorder {
+		apiHandler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), handlers.WithAllowedPlatforms(domain.PlatformKubernetes))
+		return serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance", body)
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Plans: []domain.ServicePlan{{ID: "a-plan"}}}}, nil)
+	})
+
+	It("accepts a request from an allowed platform", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","context":{"platform":"kubernetes","namespace":"a-namespace"}}`)
+		Expect(response.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("accepts a request without a context", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("rejects a request from another platform", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","context":{"platform":"cloudfoundry"}}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"platform \"cloudfoundry\" is not allowed"}`))
+		Expect(fakeServiceBroker.ProvisionCallCount()).To(BeZero())
+	})
+
+	It("rejects a request with a malformed context", func() {
+		response := provision(`{"service_id":"a-service","plan_id":"a-plan","context":{"namespace":"a-namespace"}}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"context must have a platform"}`))
+	})
+
+	It("applies to update and bind requests", func() {
+		apiHandler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), handlers.WithAllowedPlatforms(domain.PlatformKubernetes))
+		body := `{"service_id":"a-service","plan_id":"a-plan","context":{"platform":"cloudfoundry"}}`
+
+		Expect(serve(apiHandler.Update, http.MethodPatch, "/v2/service_instances/an-instance", body).Code).To(Equal(http.StatusBadRequest))
+		Expect(serve(apiHandler.Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding", body).Code).To(Equal(http.StatusBadRequest))
+
+		Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+		Expect(fakeServiceBroker.BindCallCount()).To(BeZero())
+	})
+})
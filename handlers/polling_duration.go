@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+const (
+	operationTimedOutKey = "operation-timed-out"
+
+	// timedOutRetention is how long operations that timed out are still reported as failed
+	timedOutRetention = 24 * time.Hour
+
+	// maxPollingDeadlines bounds the number of operations that are tracked, so that operations that are
+	// never polled do not pile up. When it is reached, the operation that expires first is forgotten.
+	maxPollingDeadlines = 10000
+)
+
+// TimedOutOperation is an asynchronous operation that did not finish within the maximum polling duration of its plan
+type TimedOutOperation struct {
+	Type          domain.AsyncOperation
+	InstanceID    string
+	BindingID     string
+	OperationData string
+	StartedAt     time.Time
+}
+
+// OperationTimeoutFunc is called once for an operation that has been reported as failed because it did not
+// finish within the maximum polling duration of its plan, so that the ServiceBroker can clean up. It is called
+// synchronously on the goroutine of the last operation request, which waits for it to return, and ctx is the
+// context of the request. Long clean ups should be started in the background, without ctx.
+type OperationTimeoutFunc func(ctx context.Context, operation TimedOutOperation)
+
+// WithOperationTimeout calls onTimeout when an asynchronous operation is reported as failed because it did
+// not finish within the maximum polling duration of its plan
+func WithOperationTimeout(onTimeout OperationTimeoutFunc) Option {
+	return func(h *APIHandler) {
+		h.onOperationTimeout = onTimeout
+	}
+}
+
+type pollingKey struct {
+	instanceID    string
+	bindingID     string
+	operationData string
+}
+
+// pollingDeadline is the start of an operation, and its deadline if its plan has a maximum polling duration
+type pollingDeadline struct {
+	operation TimedOutOperation
+	duration  int
+	deadline  time.Time
+	timedOut  bool
+}
+
+// pollingDeadlines tracks when asynchronous operations started, so that the Retry-After policy knows their
+// age and they fail when the maximum polling duration of their plan has passed. The deadlines of operations run by the operation engine
+// are computed from the start that the engine stores, so they survive a restart. Other deadlines are
+// kept in memory, so those operations are only failed by the process that started them while it runs.
+type pollingDeadlines struct {
+	now func() time.Time
+
+	lock      sync.Mutex
+	deadlines map[pollingKey]*pollingDeadline
+}
+
+func newPollingDeadlines() *pollingDeadlines {
+	return &pollingDeadlines{now: time.Now, deadlines: make(map[pollingKey]*pollingDeadline)}
+}
+
+// start records the start of an asynchronous operation, if its plan has a maximum polling duration
+func (p *pollingDeadlines) start(plan *domain.ServicePlan, operationType domain.AsyncOperation, instanceID, bindingID, operationData string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	for key, deadline := range p.deadlines {
+		if now.Sub(deadline.expiry()) > timedOutRetention {
+			delete(p.deadlines, key)
+		}
+	}
+
+	var duration *int
+	if plan != nil {
+		duration = plan.MaximumPollingDuration
+	}
+
+	key := pollingKey{instanceID: instanceID, bindingID: bindingID, operationData: operationData}
+	p.track(key, duration, TimedOutOperation{
+		Type:          operationType,
+		InstanceID:    instanceID,
+		BindingID:     bindingID,
+		OperationData: operationData,
+		StartedAt:     now,
+	})
+}
+
+// resume tracks an operation run by the operation engine that is not already tracked, for example because
+// the broker has restarted since it started
+func (p *pollingDeadlines) resume(plan *domain.ServicePlan, operation operations.Operation) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := pollingKey{instanceID: operation.InstanceID, bindingID: operation.BindingID, operationData: operation.ID}
+	if _, ok := p.deadlines[key]; ok || plan == nil || plan.MaximumPollingDuration == nil {
+		return
+	}
+
+	p.track(key, plan.MaximumPollingDuration, TimedOutOperation{
+		Type:          operation.Type,
+		InstanceID:    operation.InstanceID,
+		BindingID:     operation.BindingID,
+		OperationData: operation.ID,
+		StartedAt:     operation.StartedAt,
+	})
+}
+
+// track records an operation, with a deadline if there is a maximum polling duration
+func (p *pollingDeadlines) track(key pollingKey, duration *int, operation TimedOutOperation) {
+	if _, ok := p.deadlines[key]; !ok && len(p.deadlines) >= maxPollingDeadlines {
+		var earliest *pollingDeadline
+		var earliestKey pollingKey
+		for k, deadline := range p.deadlines {
+			if earliest == nil || deadline.expiry().Before(earliest.expiry()) {
+				earliest, earliestKey = deadline, k
+			}
+		}
+		delete(p.deadlines, earliestKey)
+	}
+
+	deadline := &pollingDeadline{operation: operation}
+	if duration != nil {
+		deadline.duration = *duration
+		deadline.deadline = operation.StartedAt.Add(time.Duration(*duration) * time.Second)
+	}
+	p.deadlines[key] = deadline
+}
+
+// age returns how long ago an operation that is tracked started
+func (p *pollingDeadlines) age(key pollingKey) (time.Duration, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	deadline, ok := p.deadlines[key]
+	if !ok {
+		return 0, false
+	}
+	return p.now().Sub(deadline.operation.StartedAt), true
+}
+
+// timedOut returns the state of an operation that has already timed out
+func (p *pollingDeadlines) timedOut(key pollingKey) (domain.LastOperation, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	deadline, ok := p.deadlines[key]
+	if !ok || !deadline.timedOut {
+		return domain.LastOperation{}, false
+	}
+	return deadline.lastOperation(), true
+}
+
+// enforce fails an operation that is in progress after its deadline, and returns it the first time. An operation
+// that has finished is no longer tracked.
+func (p *pollingDeadlines) enforce(key pollingKey, lastOperation domain.LastOperation) (domain.LastOperation, *TimedOutOperation) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	deadline, ok := p.deadlines[key]
+	switch {
+	case !ok:
+		return lastOperation, nil
+	case lastOperation.State != domain.InProgress:
+		delete(p.deadlines, key)
+		return lastOperation, nil
+	case deadline.timedOut:
+		return deadline.lastOperation(), nil
+	case deadline.deadline.IsZero() || p.now().Before(deadline.deadline):
+		return lastOperation, nil
+	}
+
+	deadline.timedOut = true
+	return deadline.lastOperation(), &deadline.operation
+}
+
+// expiry returns the deadline of an operation, or its start if it has no deadline
+func (d *pollingDeadline) expiry() time.Time {
+	if d.deadline.IsZero() {
+		return d.operation.StartedAt
+	}
+	return d.deadline
+}
+
+func (d *pollingDeadline) lastOperation() domain.LastOperation {
+	return domain.LastOperation{
+		State:       domain.Failed,
+		Description: fmt.Sprintf("operation did not finish within the maximum polling duration of %d seconds", d.duration),
+	}
+}
+
+// lastOperation returns the state of an operation from the operation engine if it is running the operation,
+// and from the ServiceBroker otherwise. The Retry-After policy is applied to the age of the operation when
+// it is known. An operation fails when the maximum polling duration of its plan has
+// passed, and the ServiceBroker is told about it. The plan is needed to fail operations run by the engine
+// that were started before the broker restarted.
+func (h APIHandler) lastOperation(ctx context.Context, logger blog.Blog, plan *domain.ServicePlan, instanceID, bindingID, operationData string, fromBroker func() (domain.LastOperation, error)) (domain.LastOperation, error) {
+	key := pollingKey{instanceID: instanceID, bindingID: bindingID, operationData: operationData}
+	if lastOperation, ok := h.pollingDeadlines.timedOut(key); ok {
+		return lastOperation, nil
+	}
+
+	var lastOperation domain.LastOperation
+	operation, ok, err := h.engineOperation(instanceID, bindingID, operationData)
+	switch {
+	case err != nil:
+		return domain.LastOperation{}, err
+	case ok:
+		lastOperation = h.engineLastOperation(operation)
+		h.pollingDeadlines.resume(plan, operation)
+	default:
+		if lastOperation, err = fromBroker(); err != nil {
+			return domain.LastOperation{}, err
+		}
+		if age, ok := h.pollingDeadlines.age(key); ok {
+			lastOperation.RetryAfter = h.retryAfter(lastOperation.RetryAfter, age)
+		}
+	}
+
+	lastOperation, timedOut := h.pollingDeadlines.enforce(key, lastOperation)
+	if timedOut != nil {
+		logger.Error(operationTimedOutKey, errors.New(lastOperation.Description))
+		if h.operations != nil {
+			h.operations.Cancel(operationData)
+		}
+		if h.onOperationTimeout != nil {
+			h.onOperationTimeout(ctx, *timedOut)
+		}
+	}
+	return lastOperation, nil
+}
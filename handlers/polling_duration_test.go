@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+var _ = Describe("Maximum polling duration", func() {
+	const timedOut = `{"state":"failed","description":"operation did not finish within the maximum polling duration of 1 seconds"}`
+
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		apiHandler        handlers.APIHandler
+		timeouts          chan handlers.TimedOutOperation
+		plan              domain.ServicePlan
+		now               time.Time
+	)
+
+	clock := handlers.WithClock(func() time.Time { return now })
+
+	provision := func() {
+		response := serve(apiHandler.Provision, http.MethodPut, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+	}
+
+	lastOperation := func() string {
+		return serve(apiHandler.LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?operation=provisioning", "").Body.String()
+	}
+
+	BeforeEach(func() {
+		plan = domain.ServicePlan{ID: "a-plan", MaximumPollingDuration: new(int)}
+		*plan.MaximumPollingDuration = 1
+
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{IsAsync: true, OperationData: "provisioning"}, nil)
+		fakeServiceBroker.BindReturns(domain.Binding{IsAsync: true, OperationData: "binding"}, nil)
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.InProgress}, nil)
+		fakeServiceBroker.LastBindingOperationReturns(domain.LastOperation{State: domain.InProgress}, nil)
+
+		timeouts = make(chan handlers.TimedOutOperation, 2)
+		now = time.Now()
+	})
+
+	JustBeforeEach(func() {
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Bindable: true, Plans: []domain.ServicePlan{plan}}}, nil)
+		apiHandler = handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), clock, handlers.WithOperationTimeout(func(_ context.Context, operation handlers.TimedOutOperation) {
+			timeouts <- operation
+		}))
+	})
+
+	It("fails an operation that is in progress after the maximum polling duration", func() {
+		started := now
+		provision()
+		now = started.Add(999 * time.Millisecond)
+		Expect(lastOperation()).To(MatchJSON(`{"state":"in progress"}`))
+		Expect(timeouts).To(BeEmpty())
+
+		now = started.Add(time.Second)
+		Expect(lastOperation()).To(MatchJSON(timedOut))
+		Expect(timeouts).To(Receive(Equal(handlers.TimedOutOperation{
+			Type:          domain.AsyncProvision,
+			InstanceID:    "an-instance",
+			OperationData: "provisioning",
+			StartedAt:     started,
+		})))
+	})
+
+	It("keeps reporting the failure without calling the broker or the hook again", func() {
+		provision()
+		now = now.Add(time.Second)
+		Expect(lastOperation()).To(MatchJSON(timedOut))
+		calls := fakeServiceBroker.LastOperationCallCount()
+
+		Expect(lastOperation()).To(MatchJSON(timedOut))
+		Expect(fakeServiceBroker.LastOperationCallCount()).To(Equal(calls))
+		Expect(timeouts).To(HaveLen(1))
+	})
+
+	It("reports an operation that finishes in time", func() {
+		provision()
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.Succeeded}, nil)
+		Expect(lastOperation()).To(MatchJSON(`{"state":"succeeded"}`))
+
+		now = now.Add(time.Hour)
+		Expect(lastOperation()).To(MatchJSON(`{"state":"succeeded"}`))
+		Expect(timeouts).To(BeEmpty())
+	})
+
+	It("fails binding operations", func() {
+		response := serve(apiHandler.Bind, http.MethodPut, "/v2/service_instances/an-instance/service_bindings/a-binding?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+		Expect(response.Code).To(Equal(http.StatusAccepted))
+
+		now = now.Add(time.Second)
+		Expect(serve(apiHandler.LastBindingOperation, http.MethodGet, "/v2/service_instances/an-instance/service_bindings/a-binding/last_operation?operation=binding", "").Body.String()).To(MatchJSON(timedOut))
+		Expect(timeouts).To(Receive(HaveField("BindingID", "a-binding")))
+	})
+
+	It("fails operations run by the engine from when they started, after a restart", func() {
+		engine, err := operations.NewEngine(operations.Config{Logger: slog.New(slog.NewJSONHandler(GinkgoWriter, nil))})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(engine.Shutdown, context.Background())
+
+		started, err := engine.Start(domain.AsyncProvision, "an-instance", "", func(ctx context.Context, _ func(string)) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		restarted := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), clock, handlers.WithOperationEngine(engine), handlers.WithOperationTimeout(func(_ context.Context, operation handlers.TimedOutOperation) {
+			timeouts <- operation
+		}))
+		lastOperation := func() string {
+			return serve(restarted.LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?service_id=a-service&plan_id=a-plan&operation="+started.ID, "").Body.String()
+		}
+
+		now = started.StartedAt.Add(999 * time.Millisecond)
+		Expect(lastOperation()).To(MatchJSON(`{"state":"in progress"}`))
+
+		now = started.StartedAt.Add(time.Second)
+		Expect(lastOperation()).To(MatchJSON(timedOut))
+
+		Expect(timeouts).To(Receive(Equal(handlers.TimedOutOperation{
+			Type:          domain.AsyncProvision,
+			InstanceID:    "an-instance",
+			OperationData: started.ID,
+			StartedAt:     started.StartedAt,
+		})))
+		Eventually(func() domain.LastOperationState {
+			operation, _, _ := engine.Get(started.ID)
+			return operation.State
+		}).Should(Equal(domain.Failed))
+	})
+
+	When("the plan has no maximum polling duration", func() {
+		BeforeEach(func() {
+			plan.MaximumPollingDuration = nil
+		})
+
+		It("reports the state from the broker", func() {
+			provision()
+			now = now.Add(time.Hour)
+			Expect(lastOperation()).To(MatchJSON(`{"state":"in progress"}`))
+			Expect(timeouts).To(BeEmpty())
+		})
+	})
+})
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
@@ -28,11 +28,8 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 	requestId := fmt.Sprintf("%v", req.Context().Value(middlewares.RequestIdentityKey))
 
 	var details domain.ProvisionDetails
-	if err := json.NewDecoder(req.Body).Decode(&details); err != nil {
-		logger.Error(invalidServiceDetailsErrorKey, err)
-		h.respond(w, http.StatusUnprocessableEntity, requestId, apiresponses.ErrorResponse{
-			Description: err.Error(),
-		})
+	if err := h.decodeDetails(w, req, &details); err != nil {
+		h.respondDecodeError(w, req, logger, requestId, invalidServiceDetailsErrorKey, err)
 		return
 	}
 
@@ -52,6 +49,18 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := h.checkPlatform(details); err != nil {
+		logger.Error(platformNotAllowedKey, err)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+		return
+	}
+
+	if !h.checkScope(w, req, logger, requestId, auth.Provision, details.ServiceID, details.PlanID) {
+		return
+	}
+
 	valid := false
 	services, _ := h.serviceBroker.Services(req.Context())
 	for _, service := range services {
@@ -69,17 +78,17 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	valid = false
+	var servicePlan *domain.ServicePlan
 	for _, service := range services {
 		for _, plan := range service.Plans {
 			if plan.ID == details.PlanID {
 				req = req.WithContext(utils.AddServicePlanToContext(req.Context(), &plan))
-				valid = true
+				servicePlan = &plan
 				break
 			}
 		}
 	}
-	if !valid {
+	if servicePlan == nil {
 		logger.Error(invalidPlanID, invalidPlanIDError)
 		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
 			Description: invalidPlanIDError.Error(),
@@ -87,8 +96,24 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if failure := h.checkMaintenanceInfo(details.MaintenanceInfo, servicePlan); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
+	if servicePlan.Schemas != nil && !h.checkParameters(w, logger, requestId, provisionLogKey, servicePlan.ID, servicePlan.Schemas.Instance.Create, details.RawParameters, true) {
+		return
+	}
+
 	asyncAllowed := req.FormValue("accepts_incomplete") == "true"
 
+	if failure := checkAsyncRequired(servicePlan, domain.AsyncProvision, asyncAllowed); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
 	logger = logger.With(slog.Any(instanceDetailsLogKey, details))
 
 	provisionResponse, err := h.serviceBroker.Provision(req.Context(), instanceID, details, asyncAllowed)
@@ -97,7 +122,7 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
@@ -107,6 +132,16 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if provisionResponse.Job != nil && !provisionResponse.AlreadyExists {
+		operationData, err := h.startJob(domain.AsyncProvision, instanceID, "", provisionResponse.Job, asyncAllowed)
+		if err != nil {
+			h.respondError(w, req, logger, requestId, err)
+			return
+		}
+		provisionResponse.IsAsync = true
+		provisionResponse.OperationData = operationData
+	}
+
 	var metadata any
 	if !provisionResponse.Metadata.IsEmpty() {
 		metadata = provisionResponse.Metadata
@@ -118,6 +153,8 @@ func (h APIHandler) Provision(w http.ResponseWriter, req *http.Request) {
 			Metadata:     metadata,
 		})
 	} else if provisionResponse.IsAsync {
+		h.pollingDeadlines.start(servicePlan, domain.AsyncProvision, instanceID, "", provisionResponse.OperationData)
+		setRetryAfter(w, h.retryAfter(provisionResponse.RetryAfter, 0))
 		h.respond(w, http.StatusAccepted, requestId, apiresponses.ProvisioningResponse{
 			DashboardURL:  provisionResponse.DashboardURL,
 			OperationData: provisionResponse.OperationData,
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAfterPolicy returns how long the platform should wait before polling an operation that has been
+// in progress for the age
+type RetryAfterPolicy func(age time.Duration) time.Duration
+
+// WithRetryAfterPolicy suggests a polling interval with the Retry-After header when the ServiceBroker does not.
+// The policy is used for 202 Accepted responses, and for last operation requests with the age of the operation,
+// which is known for operations run by the operation engine and for those started since the broker started.
+func WithRetryAfterPolicy(policy RetryAfterPolicy) Option {
+	return func(h *APIHandler) {
+		h.retryAfterPolicy = policy
+	}
+}
+
+// BackoffRetryAfter returns a RetryAfterPolicy that suggests polling after a tenth of the age of the
+// operation, so that operations that take a long time are polled less often, but after no less than
+// min and no more than max
+func BackoffRetryAfter(min, max time.Duration) RetryAfterPolicy {
+	return func(age time.Duration) time.Duration {
+		return clamp(age/10, min, max)
+	}
+}
+
+func clamp(d, min, max time.Duration) time.Duration {
+	switch {
+	case d < min:
+		return min
+	case d > max:
+		return max
+	default:
+		return d
+	}
+}
+
+// retryAfter returns the polling interval set by the ServiceBroker, or the interval from the policy
+// for an operation of the age
+func (h APIHandler) retryAfter(hint, age time.Duration) time.Duration {
+	if hint > 0 || h.retryAfterPolicy == nil {
+		return hint
+	}
+	return h.retryAfterPolicy(age)
+}
+
+// setRetryAfter sets the Retry-After header to the interval in whole seconds, rounded up. It must
+// be called before the response is written.
+func setRetryAfter(w http.ResponseWriter, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	seconds := int64(math.Ceil(interval.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
@@ -0,0 +1,140 @@
+package handlers_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+var _ = Describe("Retry-After", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		opts              []handlers.Option
+	)
+
+	newHandler := func() handlers.APIHandler {
+		return handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), opts...)
+	}
+
+	provision := func() *httptest.ResponseRecorder {
+		return serve(newHandler().Provision, http.MethodPut, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`)
+	}
+
+	lastOperation := func() *httptest.ResponseRecorder {
+		return serve(newHandler().LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation", "")
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		fakeServiceBroker.ServicesReturns([]domain.Service{{ID: "a-service", Plans: []domain.ServicePlan{{ID: "a-plan"}}}}, nil)
+		opts = nil
+	})
+
+	It("is set from the interval of the broker, rounded up to whole seconds", func() {
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{IsAsync: true, RetryAfter: 1500 * time.Millisecond}, nil)
+		Expect(provision().Header().Get("Retry-After")).To(Equal("2"))
+
+		fakeServiceBroker.DeprovisionReturns(domain.DeprovisionServiceSpec{IsAsync: true, RetryAfter: time.Minute}, nil)
+		Expect(serve(newHandler().Deprovision, http.MethodDelete, "/v2/service_instances/an-instance?accepts_incomplete=true&service_id=a-service&plan_id=a-plan", "").Header().Get("Retry-After")).To(Equal("60"))
+
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.InProgress, RetryAfter: 30 * time.Second}, nil)
+		Expect(lastOperation().Header().Get("Retry-After")).To(Equal("30"))
+	})
+
+	It("is not set by default", func() {
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{IsAsync: true}, nil)
+		Expect(provision().Header()).NotTo(HaveKey("Retry-After"))
+
+		fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.InProgress}, nil)
+		Expect(lastOperation().Header()).NotTo(HaveKey("Retry-After"))
+	})
+
+	It("is not set on synchronous responses", func() {
+		fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{RetryAfter: time.Minute}, nil)
+		response := provision()
+		Expect(response.Code).To(Equal(http.StatusCreated))
+		Expect(response.Header()).NotTo(HaveKey("Retry-After"))
+	})
+
+	When("there is a policy", func() {
+		BeforeEach(func() {
+			opts = []handlers.Option{handlers.WithRetryAfterPolicy(handlers.BackoffRetryAfter(5*time.Second, time.Minute))}
+		})
+
+		It("suggests the shortest interval for new operations", func() {
+			fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{IsAsync: true}, nil)
+			Expect(provision().Header().Get("Retry-After")).To(Equal("5"))
+		})
+
+		It("prefers the interval of the broker", func() {
+			fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{IsAsync: true, RetryAfter: 2 * time.Second}, nil)
+			Expect(provision().Header().Get("Retry-After")).To(Equal("2"))
+		})
+
+		It("backs off as operations run by the broker age", func() {
+			now := time.Now()
+			handler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), append(opts, handlers.WithClock(func() time.Time { return now }))...)
+			fakeServiceBroker.ProvisionReturns(domain.ProvisionedServiceSpec{IsAsync: true, OperationData: "provisioning"}, nil)
+			fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.InProgress}, nil)
+
+			started := now
+			Expect(serve(handler.Provision, http.MethodPut, "/v2/service_instances/an-instance?accepts_incomplete=true", `{"service_id":"a-service","plan_id":"a-plan"}`).Header().Get("Retry-After")).To(Equal("5"))
+
+			for _, poll := range []struct {
+				age        time.Duration
+				retryAfter string
+			}{
+				{age: 10 * time.Second, retryAfter: "5"},
+				{age: 2 * time.Minute, retryAfter: "12"},
+				{age: time.Hour, retryAfter: "60"},
+			} {
+				now = started.Add(poll.age)
+				response := serve(handler.LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?operation=provisioning", "")
+				Expect(response.Header().Get("Retry-After")).To(Equal(poll.retryAfter), "after %s", poll.age)
+			}
+		})
+
+		It("is not set for operations of the broker that were not started by this handler", func() {
+			fakeServiceBroker.LastOperationReturns(domain.LastOperation{State: domain.InProgress}, nil)
+			Expect(lastOperation().Header()).NotTo(HaveKey("Retry-After"))
+		})
+
+		It("backs off as operations run by the engine age", func() {
+			engine, err := operations.NewEngine(operations.Config{Logger: slog.New(slog.NewJSONHandler(GinkgoWriter, nil))})
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(engine.Shutdown, context.Background())
+
+			release := make(chan struct{})
+			DeferCleanup(func() { close(release) })
+			operation, err := engine.Start(domain.AsyncProvision, "an-instance", "", func(context.Context, func(string)) error {
+				<-release
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+			opts = append(opts, handlers.WithOperationEngine(engine))
+
+			response := serve(newHandler().LastOperation, http.MethodGet, "/v2/service_instances/an-instance/last_operation?operation="+operation.ID, "")
+			Expect(response.Body.String()).To(MatchJSON(`{"state":"in progress"}`))
+			Expect(response.Header().Get("Retry-After")).To(Equal("5"))
+			Expect(fakeServiceBroker.LastOperationCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("BackoffRetryAfter", func() {
+		It("suggests a tenth of the age of the operation within the limits", func() {
+			policy := handlers.BackoffRetryAfter(5*time.Second, time.Minute)
+			Expect(policy(0)).To(Equal(5 * time.Second))
+			Expect(policy(2 * time.Minute)).To(Equal(12 * time.Second))
+			Expect(policy(time.Hour)).To(Equal(time.Minute))
+		})
+	})
+})
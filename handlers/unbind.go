@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
@@ -42,13 +43,29 @@ func (h APIHandler) Unbind(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if !h.checkScope(w, req, logger, requestId, auth.Unbind, details.ServiceID, details.PlanID) {
+		return
+	}
+
 	asyncAllowed := req.FormValue("accepts_incomplete") == "true"
+
+	req, plan, ok := h.resolveCatalog(w, req, logger, requestId, details.ServiceID, details.PlanID)
+	if !ok {
+		return
+	}
+
+	if failure := checkAsyncRequired(plan, domain.AsyncUnbind, asyncAllowed); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
 	unbindResponse, err := h.serviceBroker.Unbind(req.Context(), instanceID, bindingID, details, asyncAllowed)
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
@@ -58,7 +75,19 @@ func (h APIHandler) Unbind(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if unbindResponse.Job != nil {
+		operationData, err := h.startJob(domain.AsyncUnbind, instanceID, bindingID, unbindResponse.Job, asyncAllowed)
+		if err != nil {
+			h.respondError(w, req, logger, requestId, err)
+			return
+		}
+		unbindResponse.IsAsync = true
+		unbindResponse.OperationData = operationData
+	}
+
 	if unbindResponse.IsAsync {
+		h.pollingDeadlines.start(plan, domain.AsyncUnbind, instanceID, bindingID, unbindResponse.OperationData)
+		setRetryAfter(w, h.retryAfter(unbindResponse.RetryAfter, 0))
 		h.respond(w, http.StatusAccepted, requestId, apiresponses.UnbindResponse{
 			OperationData: unbindResponse.OperationData,
 		})
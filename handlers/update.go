@@ -1,20 +1,30 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
 )
 
 const updateLogKey = "update"
 
+// planUpdatable reports whether an instance can change from the plan to another plan. The setting of the
+// plan overrides that of the service.
+func planUpdatable(service *domain.Service, plan *domain.ServicePlan) bool {
+	if plan != nil && plan.PlanUpdatable != nil {
+		return *plan.PlanUpdatable
+	}
+	return service.PlanUpdatable
+}
+
 func (h APIHandler) Update(w http.ResponseWriter, req *http.Request) {
 	instanceID := req.PathValue("instance_id")
 
@@ -23,11 +33,8 @@ func (h APIHandler) Update(w http.ResponseWriter, req *http.Request) {
 	requestId := fmt.Sprintf("%v", req.Context().Value(middlewares.RequestIdentityKey))
 
 	var details domain.UpdateDetails
-	if err := json.NewDecoder(req.Body).Decode(&details); err != nil {
-		logger.Error(invalidServiceDetailsErrorKey, err)
-		h.respond(w, http.StatusUnprocessableEntity, requestId, apiresponses.ErrorResponse{
-			Description: err.Error(),
-		})
+	if err := h.decodeDetails(w, req, &details); err != nil {
+		h.respondDecodeError(w, req, logger, requestId, invalidServiceDetailsErrorKey, err)
 		return
 	}
 
@@ -39,14 +46,81 @@ func (h APIHandler) Update(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := h.checkPlatform(details); err != nil {
+		logger.Error(platformNotAllowedKey, err)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: err.Error(),
+		})
+		return
+	}
+
+	if !h.checkScope(w, req, logger, requestId, auth.Update, details.ServiceID, details.PlanID, details.PreviousValues.PlanID) {
+		return
+	}
+
+	service, plan := h.findInCatalog(req.Context(), details.ServiceID, details.PlanID)
+	if service == nil {
+		logger.Error(invalidServiceID, invalidServiceIDError)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: invalidServiceIDError.Error(),
+		})
+		return
+	}
+	req = req.WithContext(utils.AddServiceToContext(req.Context(), service))
+
+	// The previous plan may have been removed from the catalog, so it is not required
+	previousPlan := findPlan(service, details.PreviousValues.PlanID)
+	req = req.WithContext(utils.AddPreviousServicePlanToContext(req.Context(), previousPlan))
+
+	switch {
+	case details.PlanID == "":
+		plan = previousPlan
+	case plan == nil && details.PreviousValues.PlanID != "" && details.PlanID != details.PreviousValues.PlanID:
+		logger.Error(invalidPlanID, invalidPlanIDError)
+		h.respond(w, http.StatusBadRequest, requestId, apiresponses.ErrorResponse{
+			Description: invalidPlanIDError.Error(),
+		})
+		return
+	case plan == nil:
+		// The instance may be on a plan that has been retired from the catalog, which is not a plan change
+		if !h.unknownID(w, logger, requestId, invalidPlanID, invalidPlanIDError) {
+			return
+		}
+	}
+	req = req.WithContext(utils.AddServicePlanToContext(req.Context(), plan))
+
+	// Without previous_values it is not known whether the plan is changing, so the request is allowed
+	if details.PlanID != "" && details.PreviousValues.PlanID != "" && details.PlanID != details.PreviousValues.PlanID && !planUpdatable(service, previousPlan) {
+		failure := apiresponses.ErrPlanChangeNotSupported
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
+	if failure := h.checkMaintenanceInfo(details.MaintenanceInfo, plan); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
+	if plan != nil && plan.Schemas != nil && !h.checkParameters(w, logger, requestId, updateLogKey, plan.ID, plan.Schemas.Instance.Update, details.RawParameters, false) {
+		return
+	}
+
 	acceptsIncompleteFlag, _ := strconv.ParseBool(req.URL.Query().Get("accepts_incomplete"))
 
+	if failure := checkAsyncRequired(plan, domain.AsyncUpdate, acceptsIncompleteFlag); failure != nil {
+		logger.Error(failure.LoggerAction(), failure)
+		h.respond(w, failure.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(failure, getAPIVersion(req)))
+		return
+	}
+
 	updateServiceSpec, err := h.serviceBroker.Update(req.Context(), instanceID, details, acceptsIncompleteFlag)
 	if err != nil {
 		switch err := err.(type) {
 		case *apiresponses.FailureResponse:
 			logger.Error(err.LoggerAction(), err)
-			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, err.ErrorResponse())
+			h.respond(w, err.ValidatedStatusCode(slog.New(logger)), requestId, failureResponse(err, getAPIVersion(req)))
 		default:
 			logger.Error(unknownErrorKey, err)
 			h.respond(w, http.StatusInternalServerError, requestId, apiresponses.ErrorResponse{
@@ -56,6 +130,16 @@ func (h APIHandler) Update(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if updateServiceSpec.Job != nil {
+		operationData, err := h.startJob(domain.AsyncUpdate, instanceID, "", updateServiceSpec.Job, acceptsIncompleteFlag)
+		if err != nil {
+			h.respondError(w, req, logger, requestId, err)
+			return
+		}
+		updateServiceSpec.IsAsync = true
+		updateServiceSpec.OperationData = operationData
+	}
+
 	var metadata any
 	if !updateServiceSpec.Metadata.IsEmpty() {
 		metadata = updateServiceSpec.Metadata
@@ -64,6 +148,8 @@ func (h APIHandler) Update(w http.ResponseWriter, req *http.Request) {
 	statusCode := http.StatusOK
 	if updateServiceSpec.IsAsync {
 		statusCode = http.StatusAccepted
+		h.pollingDeadlines.start(plan, domain.AsyncUpdate, instanceID, "", updateServiceSpec.OperationData)
+		setRetryAfter(w, h.retryAfter(updateServiceSpec.RetryAfter, 0))
 	}
 	h.respond(w, statusCode, requestId, apiresponses.UpdateResponse{
 		OperationData: updateServiceSpec.OperationData,
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	brokerFakes "github.com/pivotal-cf/brokerapi/v12/fakes"
+	"github.com/pivotal-cf/brokerapi/v12/handlers"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
+)
+
+var _ = Describe("Update", func() {
+	var (
+		fakeServiceBroker *brokerFakes.AutoFakeServiceBroker
+		service           domain.Service
+		opts              []handlers.Option
+	)
+
+	update := func(body string) *httptest.ResponseRecorder {
+		fakeServiceBroker.ServicesReturns([]domain.Service{service}, nil)
+		apiHandler := handlers.NewApiHandler(fakeServiceBroker, slog.New(slog.NewJSONHandler(GinkgoWriter, nil)), opts...)
+		return serve(apiHandler.Update, http.MethodPatch, "/v2/service_instances/an-instance", body)
+	}
+
+	BeforeEach(func() {
+		fakeServiceBroker = new(brokerFakes.AutoFakeServiceBroker)
+		service = domain.Service{
+			ID:            "a-service",
+			PlanUpdatable: true,
+			Plans:         []domain.ServicePlan{{ID: "small"}, {ID: "large"}},
+		}
+		opts = nil
+	})
+
+	It("adds the service, the new plan and the previous plan to the context", func() {
+		response := update(`{"service_id":"a-service","plan_id":"large","previous_values":{"plan_id":"small"}}`)
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		ctx, _, _, _ := fakeServiceBroker.UpdateArgsForCall(0)
+		Expect(utils.RetrieveServiceFromContext(ctx).ID).To(Equal("a-service"))
+		Expect(utils.RetrieveServicePlanFromContext(ctx).ID).To(Equal("large"))
+		Expect(utils.RetrievePreviousServicePlanFromContext(ctx).ID).To(Equal("small"))
+	})
+
+	It("uses the previous plan as the plan when the plan is not changing", func() {
+		Expect(update(`{"service_id":"a-service","previous_values":{"plan_id":"small"}}`).Code).To(Equal(http.StatusOK))
+
+		ctx, _, _, _ := fakeServiceBroker.UpdateArgsForCall(0)
+		Expect(utils.RetrieveServicePlanFromContext(ctx).ID).To(Equal("small"))
+		Expect(utils.RetrievePreviousServicePlanFromContext(ctx).ID).To(Equal("small"))
+	})
+
+	It("allows the previous plan to be missing from the catalog", func() {
+		Expect(update(`{"service_id":"a-service","plan_id":"large","previous_values":{"plan_id":"retired"}}`).Code).To(Equal(http.StatusOK))
+
+		ctx, _, _, _ := fakeServiceBroker.UpdateArgsForCall(0)
+		Expect(utils.RetrievePreviousServicePlanFromContext(ctx)).To(BeNil())
+	})
+
+	It("rejects a service that is not in the catalog", func() {
+		response := update(`{"service_id":"other-service","plan_id":"large"}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"service-id not in the catalog"}`))
+		Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+	})
+
+	It("rejects a plan that is not in the catalog", func() {
+		response := update(`{"service_id":"a-service","plan_id":"huge","previous_values":{"plan_id":"small"}}`)
+		Expect(response.Code).To(Equal(http.StatusBadRequest))
+		Expect(response.Body.String()).To(MatchJSON(`{"description":"plan-id not in the catalog"}`))
+		Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+	})
+
+	Describe("an instance on a plan that is not in the catalog", func() {
+		const body = `{"service_id":"a-service","plan_id":"retired","previous_values":{"plan_id":"retired"},"parameters":{"a":1}}`
+
+		It("is updated without a plan in the context by default", func() {
+			Expect(update(body).Code).To(Equal(http.StatusOK))
+
+			ctx, _, _, _ := fakeServiceBroker.UpdateArgsForCall(0)
+			Expect(utils.RetrieveServicePlanFromContext(ctx)).To(BeNil())
+			Expect(utils.RetrievePreviousServicePlanFromContext(ctx)).To(BeNil())
+		})
+
+		It("is updated without previous values", func() {
+			Expect(update(`{"service_id":"a-service","plan_id":"retired","parameters":{"a":1}}`).Code).To(Equal(http.StatusOK))
+		})
+
+		It("is rejected with CatalogResolutionReject", func() {
+			opts = []handlers.Option{handlers.WithCatalogResolution(handlers.CatalogResolutionReject)}
+
+			response := update(body)
+			Expect(response.Code).To(Equal(http.StatusBadRequest))
+			Expect(response.Body.String()).To(MatchJSON(`{"description":"plan-id not in the catalog"}`))
+			Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+		})
+
+		It("cannot change to another plan that is not in the catalog", func() {
+			Expect(update(`{"service_id":"a-service","plan_id":"huge","previous_values":{"plan_id":"retired"}}`).Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	When("the service does not allow plan changes", func() {
+		BeforeEach(func() {
+			service.PlanUpdatable = false
+		})
+
+		It("rejects a plan change", func() {
+			response := update(`{"service_id":"a-service","plan_id":"large","previous_values":{"plan_id":"small"}}`)
+			Expect(response.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(response.Body.String()).To(MatchJSON(`{
+				"error": "PlanChangeNotSupported",
+				"description": "The requested plan migration cannot be performed"
+			}`))
+			Expect(fakeServiceBroker.UpdateCallCount()).To(BeZero())
+		})
+
+		It("allows other updates", func() {
+			Expect(update(`{"service_id":"a-service","plan_id":"small","previous_values":{"plan_id":"small"},"parameters":{"a":1}}`).Code).To(Equal(http.StatusOK))
+			Expect(update(`{"service_id":"a-service","previous_values":{"plan_id":"small"},"parameters":{"a":1}}`).Code).To(Equal(http.StatusOK))
+		})
+
+		It("allows updates without previous values", func() {
+			Expect(update(`{"service_id":"a-service","plan_id":"small","parameters":{"a":1}}`).Code).To(Equal(http.StatusOK))
+			Expect(fakeServiceBroker.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("allows a plan change when the previous plan overrides the service", func() {
+			service.Plans[0].PlanUpdatable = domain.PlanUpdatableValue(true)
+			Expect(update(`{"service_id":"a-service","plan_id":"large","previous_values":{"plan_id":"small"}}`).Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	It("rejects a plan change when the previous plan is not updatable", func() {
+		service.Plans[0].PlanUpdatable = domain.PlanUpdatableValue(false)
+		Expect(update(`{"service_id":"a-service","plan_id":"large","previous_values":{"plan_id":"small"}}`).Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
@@ -13,13 +13,17 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/pivotal-cf/brokerapi/v12/auth"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
 )
 
 const (
-	instanceIDLogKey = "instance-id"
-	bindingIDLogKey  = "binding-id"
-	errorKey         = "error"
+	instanceIDLogKey       = "instance-id"
+	bindingIDLogKey        = "binding-id"
+	errorKey               = "error"
+	principalKey           = "principal"
+	originatingIdentityKey = "originating-identity"
 )
 
 type Blog struct {
@@ -40,6 +44,21 @@ func (b Blog) Session(ctx context.Context, prefix string, attr ...any) Blog {
 		}
 	}
 
+	if principal := auth.RetrievePrincipalFromContext(ctx); principal != nil {
+		attr = append(attr, slog.Group(principalKey,
+			slog.String("name", principal.Name),
+			slog.String("scheme", string(principal.Scheme)),
+			slog.String("label", principal.Label),
+		))
+	}
+
+	if identity := utils.RetrieveOriginatingIdentityFromContext(ctx); identity != nil {
+		attr = append(attr, slog.Group(originatingIdentityKey,
+			slog.String("platform", identity.Platform),
+			slog.String("user", identity.User()),
+		))
+	}
+
 	return Blog{
 		logger: b.logger.With(attr...),
 		prefix: appendPrefix(b.prefix, prefix),
@@ -56,6 +75,11 @@ func (b Blog) Info(message string, attr ...any) {
 	b.logger.Info(join(b.prefix, message), attr...)
 }
 
+// Warn logs a warning about an error, in the same way as Error()
+func (b Blog) Warn(message string, err error, attr ...any) {
+	b.logger.Warn(join(b.prefix, message), append([]any{slog.Any(errorKey, err)}, attr...)...)
+}
+
 // With returns a logger that always logs the specified attributes
 func (b Blog) With(attr ...any) Blog {
 	b.logger = b.logger.With(attr...)
@@ -8,8 +8,11 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/brokerapi/v12/auth"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
 	"github.com/pivotal-cf/brokerapi/v12/internal/blog"
 	"github.com/pivotal-cf/brokerapi/v12/middlewares"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
 )
 
 var _ = Describe("Context data", func() {
@@ -49,6 +52,53 @@ var _ = Describe("Context data", func() {
 
 			Expect(receiver).NotTo(HaveKey(string(middlewares.CorrelationIDKey)))
 			Expect(receiver).NotTo(HaveKey(string(middlewares.RequestIdentityKey)))
+			Expect(receiver).NotTo(HaveKey("principal"))
+		})
+	})
+
+	When("the context has an authenticated principal", func() {
+		It("logs the principal", func() {
+			ctx := auth.AddPrincipalToContext(context.TODO(), &auth.Principal{
+				Name:   "platform-user",
+				Scheme: auth.SchemeBasic,
+				Label:  "cf-production",
+			})
+
+			buffer := gbytes.NewBuffer()
+			logger := slog.New(slog.NewJSONHandler(buffer, nil))
+
+			blog.New(logger).Session(ctx, "prefix").Info("hello")
+
+			var receiver map[string]any
+			Expect(json.Unmarshal(buffer.Contents(), &receiver)).To(Succeed())
+
+			Expect(receiver).To(HaveKeyWithValue("principal", map[string]any{
+				"name":   "platform-user",
+				"scheme": "basic",
+				"label":  "cf-production",
+			}))
+		})
+	})
+
+	When("the context has an originating identity", func() {
+		It("logs the platform and user", func() {
+			ctx := utils.AddOriginatingIdentityToContext(context.TODO(), &domain.OriginatingIdentity{
+				Platform: "kubernetes",
+				Value:    []byte(`{"username":"duke","uid":"c2dde242"}`),
+			})
+
+			buffer := gbytes.NewBuffer()
+			logger := slog.New(slog.NewJSONHandler(buffer, nil))
+
+			blog.New(logger).Session(ctx, "prefix").Info("hello")
+
+			var receiver map[string]any
+			Expect(json.Unmarshal(buffer.Contents(), &receiver)).To(Succeed())
+
+			Expect(receiver).To(HaveKeyWithValue("originating-identity", map[string]any{
+				"platform": "kubernetes",
+				"user":     "duke",
+			}))
 		})
 	})
 })
@@ -0,0 +1,897 @@
+// Package jsonschema validates JSON values against the subset of JSON Schema (draft-04 to 2020-12)
+// that is commonly used for service broker parameter schemas. Only local references ("#...") are
+// resolved, so validation never causes network access. The "format" keyword is treated as an
+// annotation, and "pattern" uses Go regular expression syntax (RE2).
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation describes where and how a value does not conform to a schema. Path is a JSON pointer
+// to the location in the value, Keyword is the schema keyword that failed.
+type Violation struct {
+	Path    string
+	Keyword string
+	Message string
+}
+
+// Schema is a compiled schema
+type Schema struct {
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+const (
+	maxRefDepth = 64
+
+	// maxNumberLength and maxExponent limit the numbers that are compared exactly, since converting a
+	// number such as 1e1000000 to an exact value takes a long time
+	maxNumberLength = 1000
+	maxExponent     = 1000
+)
+
+var errNumberTooLarge = fmt.Errorf("numbers must have at most %d characters and an exponent between %d and %d", maxNumberLength, -maxExponent, maxExponent)
+
+// Compile checks a schema, returning an error if it contains a remote reference,
+// an unresolvable local reference, or an invalid regular expression
+func Compile(schema any) (*Schema, error) {
+	root, err := normalize(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.check(root, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate checks a JSON document against the schema. An error is returned if the document is not
+// valid JSON, or contains a number that is too large or too precise to compare.
+func (s *Schema) Validate(document []byte) ([]Violation, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if err := checkNumbers(value); err != nil {
+		return nil, err
+	}
+
+	v := validation{schema: s, refs: make(map[refKey][]Violation)}
+	v.validate(s.root, value, "", 0)
+	sort.SliceStable(v.violations, func(i, j int) bool { return v.violations[i].Path < v.violations[j].Path })
+	return v.violations, nil
+}
+
+// normalize converts a schema to the types produced by decoding JSON with UseNumber(),
+// so that schemas written as Go literals behave the same as decoded schemas
+func normalize(schema any) (any, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var result any
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return result, nil
+}
+
+var (
+	types = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+	subschemaKeywords      = []string{"additionalProperties", "additionalItems", "contains", "not", "if", "then", "else", "propertyNames"}
+	subschemaMapKeywords   = []string{"properties", "patternProperties", "definitions", "$defs", "dependentSchemas"}
+	subschemaArrayKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+	numberKeywords         = []string{"minimum", "maximum"}
+	countKeywords          = []string{"minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties", "minContains", "maxContains"}
+)
+
+// check reports the first problem found in a schema and the schemas it contains or references
+func (s *Schema) check(schema any, path string) error {
+	return s.checkSchema(schema, path, make(map[string]bool))
+}
+
+func (s *Schema) checkSchema(schema any, path string, checkedRefs map[string]bool) error {
+	object, ok := schema.(map[string]any)
+	if !ok {
+		if _, ok := schema.(bool); ok {
+			return nil
+		}
+		return &schemaError{path: path, err: errors.New("schema must be an object or a boolean")}
+	}
+
+	keywords := make([]string, 0, len(object))
+	for keyword := range object {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		value := object[keyword]
+		keywordPath := path + "/" + escape(keyword)
+
+		var err error
+		switch {
+		case keyword == "$ref":
+			err = s.checkRef(value, keywordPath, checkedRefs)
+		case keyword == "type":
+			err = checkType(value)
+		case keyword == "pattern":
+			err = s.compilePattern(value)
+		case keyword == "required" || keyword == "enum":
+			if _, ok := value.([]any); !ok {
+				err = errors.New("must be an array")
+			}
+		case keyword == "items":
+			if items, ok := value.([]any); ok {
+				err = s.checkSchemas(items, keywordPath, checkedRefs)
+			} else {
+				err = s.checkSchema(value, keywordPath, checkedRefs)
+			}
+		case keyword == "dependencies":
+			err = s.checkDependencies(value, keywordPath, checkedRefs)
+		case keyword == "multipleOf":
+			if n, ok := toRat(value); !ok || n.Sign() <= 0 {
+				err = errors.New("must be a number greater than 0")
+			}
+		case keyword == "exclusiveMinimum" || keyword == "exclusiveMaximum":
+			if _, ok := value.(bool); !ok {
+				if _, ok := toRat(value); !ok {
+					err = errors.New("must be a boolean or a number")
+				}
+			}
+		case keyword == "uniqueItems":
+			if _, ok := value.(bool); !ok {
+				err = errors.New("must be a boolean")
+			}
+		case slices.Contains(numberKeywords, keyword):
+			if _, ok := toRat(value); !ok {
+				err = errors.New("must be a number")
+			}
+		case slices.Contains(countKeywords, keyword):
+			if n, ok := toRat(value); !ok || !n.IsInt() || n.Sign() < 0 {
+				err = errors.New("must be a non-negative integer")
+			}
+		case slices.Contains(subschemaKeywords, keyword):
+			err = s.checkSchema(value, keywordPath, checkedRefs)
+		case slices.Contains(subschemaArrayKeywords, keyword):
+			items, ok := value.([]any)
+			if !ok || len(items) == 0 {
+				err = errors.New("must be a non-empty array")
+			} else {
+				err = s.checkSchemas(items, keywordPath, checkedRefs)
+			}
+		case slices.Contains(subschemaMapKeywords, keyword):
+			err = s.checkSchemaMap(keyword, value, keywordPath, checkedRefs)
+		}
+
+		if err != nil {
+			var schemaErr *schemaError
+			if errors.As(err, &schemaErr) {
+				return err
+			}
+			return &schemaError{path: keywordPath, err: err}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) checkRef(value any, path string, checkedRefs map[string]bool) error {
+	ref, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return fmt.Errorf("remote reference %q is not supported", ref)
+	}
+
+	target, err := s.resolve(ref)
+	if err != nil {
+		return err
+	}
+	if checkedRefs[ref] {
+		return nil
+	}
+	checkedRefs[ref] = true
+	return s.checkSchema(target, strings.TrimPrefix(ref, "#"), checkedRefs)
+}
+
+func (s *Schema) checkSchemas(schemas []any, path string, checkedRefs map[string]bool) error {
+	for i, schema := range schemas {
+		if err := s.checkSchema(schema, path+"/"+strconv.Itoa(i), checkedRefs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) checkSchemaMap(keyword string, value any, path string, checkedRefs map[string]bool) error {
+	schemas, ok := value.(map[string]any)
+	if !ok {
+		return errors.New("must be an object")
+	}
+
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if keyword == "patternProperties" {
+			if err := s.compilePattern(name); err != nil {
+				return err
+			}
+		}
+		if err := s.checkSchema(schemas[name], path+"/"+escape(name), checkedRefs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDependencies checks the draft-04 "dependencies" keyword, whose values are schemas or arrays of property names
+func (s *Schema) checkDependencies(value any, path string, checkedRefs map[string]bool) error {
+	dependencies, ok := value.(map[string]any)
+	if !ok {
+		return errors.New("must be an object")
+	}
+
+	for name, dependency := range dependencies {
+		if _, ok := dependency.([]any); ok {
+			continue
+		}
+		if err := s.checkSchema(dependency, path+"/"+escape(name), checkedRefs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkType(value any) error {
+	var names []any
+	switch value := value.(type) {
+	case string:
+		names = []any{value}
+	case []any:
+		names = value
+	default:
+		return errors.New("must be a string or an array of strings")
+	}
+
+	for _, name := range names {
+		if n, ok := name.(string); !ok || !slices.Contains(types, n) {
+			return fmt.Errorf("unknown type %s", marshal(name))
+		}
+	}
+	return nil
+}
+
+// schemaError is a problem with a schema, at a JSON pointer within the schema
+type schemaError struct {
+	path string
+	err  error
+}
+
+func (e *schemaError) Error() string {
+	return pathOrRoot(e.path) + ": " + e.err.Error()
+}
+
+func (e *schemaError) Unwrap() error {
+	return e.err
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func (s *Schema) compilePattern(pattern any) error {
+	p, ok := pattern.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	if _, ok := s.patterns[p]; ok {
+		return nil
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return err
+	}
+	s.patterns[p] = re
+	return nil
+}
+
+// resolve finds the schema for a local reference such as "#", "#/definitions/name" or "#/$defs/name"
+func (s *Schema) resolve(ref string) (any, error) {
+	fragment, err := url.PathUnescape(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+	if fragment == "" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		return nil, fmt.Errorf("reference %q is not a JSON pointer", ref)
+	}
+
+	current := s.root
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch c := current.(type) {
+		case map[string]any:
+			next, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("reference %q cannot be resolved", ref)
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("reference %q cannot be resolved", ref)
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("reference %q cannot be resolved", ref)
+		}
+	}
+	return current, nil
+}
+
+type validation struct {
+	schema     *Schema
+	violations []Violation
+
+	// refs holds the violations found by following a reference at a path, so that schemas such as
+	// {"anyOf":[{"$ref":"#"},{"$ref":"#"}]} check each reference once instead of once per branch
+	refs map[refKey][]Violation
+}
+
+type refKey struct {
+	ref  string
+	path string
+}
+
+func (v *validation) fail(path, keyword, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+// valid checks a value against a subschema without recording violations
+func (v *validation) valid(schema, value any, path string, depth int) bool {
+	sub := validation{schema: v.schema, refs: v.refs}
+	sub.validate(schema, value, path, depth)
+	return len(sub.violations) == 0
+}
+
+// validName checks a property name against a subschema. The name is not the value at the path,
+// so references are followed separately from those of the value.
+func (v *validation) validName(schema any, name, path string, depth int) bool {
+	sub := validation{schema: v.schema, refs: make(map[refKey][]Violation)}
+	sub.validate(schema, name, path, depth)
+	return len(sub.violations) == 0
+}
+
+func (v *validation) validate(schema, value any, path string, depth int) {
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			v.fail(path, "false", "no value is allowed")
+		}
+		return
+	case map[string]any:
+		v.validateObject(schema, value, path, depth)
+	}
+}
+
+func (v *validation) validateObject(schema map[string]any, value any, path string, depth int) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			v.fail(path, "$ref", "too many nested references")
+			return
+		}
+		v.validateRef(ref, value, path, depth)
+	}
+
+	v.validateType(schema, value, path)
+	v.validateEnum(schema, value, path)
+	v.validateCombinators(schema, value, path, depth)
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateProperties(schema, value, path, depth)
+	case []any:
+		v.validateItems(schema, value, path, depth)
+	case string:
+		v.validateString(schema, value, path)
+	case json.Number:
+		v.validateNumber(schema, value, path)
+	}
+}
+
+func (v *validation) validateRef(ref string, value any, path string, depth int) {
+	key := refKey{ref: ref, path: path}
+	violations, ok := v.refs[key]
+	if !ok {
+		target, err := v.schema.resolve(ref)
+		if err != nil {
+			v.fail(path, "$ref", "%s", err)
+			return
+		}
+
+		sub := validation{schema: v.schema, refs: v.refs}
+		sub.validate(target, value, path, depth+1)
+		violations = sub.violations
+		v.refs[key] = violations
+	}
+	v.violations = append(v.violations, violations...)
+}
+
+func (v *validation) validateType(schema map[string]any, value any, path string) {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	default:
+		return
+	}
+
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return
+		}
+	}
+	v.fail(path, "type", "must be %s, but is %s", strings.Join(types, " or "), actual)
+}
+
+func (v *validation) validateEnum(schema map[string]any, value any, path string) {
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(e any) bool { return equal(e, value) }) {
+			v.fail(path, "enum", "must be one of %s", marshal(enum))
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !equal(c, value) {
+		v.fail(path, "const", "must be %s", marshal(c))
+	}
+}
+
+func (v *validation) validateCombinators(schema map[string]any, value any, path string, depth int) {
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, s := range allOf {
+			v.validate(s, value, path, depth)
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if !slices.ContainsFunc(anyOf, func(s any) bool { return v.valid(s, value, path, depth) }) {
+			v.fail(path, "anyOf", "must match at least one schema in anyOf")
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, s := range oneOf {
+			if v.valid(s, value, path, depth) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "oneOf", "must match exactly one schema in oneOf, but matches %d", matches)
+		}
+	}
+
+	if not, ok := schema["not"]; ok && v.valid(not, value, path, depth) {
+		v.fail(path, "not", "must not match the schema in not")
+	}
+
+	if ifSchema, ok := schema["if"]; ok {
+		if v.valid(ifSchema, value, path, depth) {
+			if then, ok := schema["then"]; ok {
+				v.validate(then, value, path, depth)
+			}
+		} else if elseSchema, ok := schema["else"]; ok {
+			v.validate(elseSchema, value, path, depth)
+		}
+	}
+}
+
+func (v *validation) validateProperties(schema map[string]any, object map[string]any, path string, depth int) {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := object[name]; !present {
+					v.fail(path, "required", "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	if n, ok := toRat(schema["minProperties"]); ok && new(big.Rat).SetInt64(int64(len(object))).Cmp(n) < 0 {
+		v.fail(path, "minProperties", "must have at least %s properties", n.RatString())
+	}
+	if n, ok := toRat(schema["maxProperties"]); ok && new(big.Rat).SetInt64(int64(len(object))).Cmp(n) > 0 {
+		v.fail(path, "maxProperties", "must have at most %s properties", n.RatString())
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	patternProperties, _ := schema["patternProperties"].(map[string]any)
+	additional, hasAdditional := schema["additionalProperties"]
+
+	for _, name := range names {
+		propertyPath := path + "/" + escape(name)
+		matched := false
+
+		if s, ok := properties[name]; ok {
+			matched = true
+			v.validate(s, object[name], propertyPath, depth)
+		}
+
+		for pattern, s := range patternProperties {
+			if v.schema.patterns[pattern].MatchString(name) {
+				matched = true
+				v.validate(s, object[name], propertyPath, depth)
+			}
+		}
+
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				v.fail(propertyPath, "additionalProperties", "property %q is not allowed", name)
+			} else {
+				v.validate(additional, object[name], propertyPath, depth)
+			}
+		}
+
+		if propertyNames, ok := schema["propertyNames"]; ok && !v.validName(propertyNames, name, propertyPath, depth) {
+			v.fail(propertyPath, "propertyNames", "property name %q is not valid", name)
+		}
+	}
+
+	v.validateDependencies(schema, object, path, depth)
+}
+
+func (v *validation) validateDependencies(schema map[string]any, object map[string]any, path string, depth int) {
+	dependencies := make(map[string]any)
+	for _, keyword := range []string{"dependencies", "dependentRequired", "dependentSchemas"} {
+		if d, ok := schema[keyword].(map[string]any); ok {
+			for name, dependency := range d {
+				dependencies[name] = dependency
+			}
+		}
+	}
+
+	for name, dependency := range dependencies {
+		if _, present := object[name]; !present {
+			continue
+		}
+
+		if required, ok := dependency.([]any); ok {
+			for _, r := range required {
+				if dep, ok := r.(string); ok {
+					if _, present := object[dep]; !present {
+						v.fail(path, "dependentRequired", "property %q is required when %q is present", dep, name)
+					}
+				}
+			}
+		} else {
+			v.validate(dependency, object, path, depth)
+		}
+	}
+}
+
+func (v *validation) validateItems(schema map[string]any, array []any, path string, depth int) {
+	if n, ok := toRat(schema["minItems"]); ok && new(big.Rat).SetInt64(int64(len(array))).Cmp(n) < 0 {
+		v.fail(path, "minItems", "must have at least %s items", n.RatString())
+	}
+	if n, ok := toRat(schema["maxItems"]); ok && new(big.Rat).SetInt64(int64(len(array))).Cmp(n) > 0 {
+		v.fail(path, "maxItems", "must have at most %s items", n.RatString())
+	}
+
+	if unique, ok := schema["uniqueItems"].(bool); ok && unique {
+		seen := make(map[string]int, len(array))
+		for j, item := range array {
+			key := canonical(item)
+			if i, ok := seen[key]; ok {
+				v.fail(path, "uniqueItems", "items %d and %d are equal", i, j)
+				break
+			}
+			seen[key] = j
+		}
+	}
+
+	// The leading items are checked against "prefixItems" (2020-12) or an array of "items" (earlier drafts),
+	// and the rest against "items" (2020-12) or "additionalItems" (earlier drafts)
+	var tuple []any
+	var rest any
+	if prefixItems, ok := schema["prefixItems"].([]any); ok {
+		tuple, rest = prefixItems, schema["items"]
+	} else if items, ok := schema["items"].([]any); ok {
+		tuple, rest = items, schema["additionalItems"]
+	} else {
+		rest = schema["items"]
+	}
+
+	for i, item := range array {
+		itemPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case i < len(tuple):
+			v.validate(tuple[i], item, itemPath, depth)
+		case rest != nil:
+			v.validate(rest, item, itemPath, depth)
+		}
+	}
+
+	if contains, ok := schema["contains"]; ok {
+		matches := 0
+		for i, item := range array {
+			if v.valid(contains, item, path+"/"+strconv.Itoa(i), depth) {
+				matches++
+			}
+		}
+
+		minContains := big.NewRat(1, 1)
+		if n, ok := toRat(schema["minContains"]); ok {
+			minContains = n
+		}
+		if big.NewRat(int64(matches), 1).Cmp(minContains) < 0 {
+			v.fail(path, "contains", "must contain at least %s matching items", minContains.RatString())
+		}
+		if n, ok := toRat(schema["maxContains"]); ok && big.NewRat(int64(matches), 1).Cmp(n) > 0 {
+			v.fail(path, "maxContains", "must contain at most %s matching items", n.RatString())
+		}
+	}
+}
+
+func (v *validation) validateString(schema map[string]any, s string, path string) {
+	length := big.NewRat(int64(utf8.RuneCountInString(s)), 1)
+	if n, ok := toRat(schema["minLength"]); ok && length.Cmp(n) < 0 {
+		v.fail(path, "minLength", "must be at least %s characters long", n.RatString())
+	}
+	if n, ok := toRat(schema["maxLength"]); ok && length.Cmp(n) > 0 {
+		v.fail(path, "maxLength", "must be at most %s characters long", n.RatString())
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok && !v.schema.patterns[pattern].MatchString(s) {
+		v.fail(path, "pattern", "must match the pattern %q", pattern)
+	}
+}
+
+func (v *validation) validateNumber(schema map[string]any, number json.Number, path string) {
+	n, ok := toRat(number)
+	if !ok {
+		return
+	}
+
+	// In draft-04 exclusiveMinimum and exclusiveMaximum are booleans that modify minimum and maximum,
+	// and in later drafts they are numbers
+	exclusiveMinimum, _ := schema["exclusiveMinimum"].(bool)
+	exclusiveMaximum, _ := schema["exclusiveMaximum"].(bool)
+
+	if min, ok := toRat(schema["minimum"]); ok {
+		if c := n.Cmp(min); c < 0 || (exclusiveMinimum && c == 0) {
+			v.fail(path, "minimum", "must be %s %s", comparison(">", exclusiveMinimum), min.FloatString(precision(min)))
+		}
+	}
+	if max, ok := toRat(schema["maximum"]); ok {
+		if c := n.Cmp(max); c > 0 || (exclusiveMaximum && c == 0) {
+			v.fail(path, "maximum", "must be %s %s", comparison("<", exclusiveMaximum), max.FloatString(precision(max)))
+		}
+	}
+	if min, ok := toRat(schema["exclusiveMinimum"]); ok && n.Cmp(min) <= 0 {
+		v.fail(path, "exclusiveMinimum", "must be > %s", min.FloatString(precision(min)))
+	}
+	if max, ok := toRat(schema["exclusiveMaximum"]); ok && n.Cmp(max) >= 0 {
+		v.fail(path, "exclusiveMaximum", "must be < %s", max.FloatString(precision(max)))
+	}
+
+	if multipleOf, ok := toRat(schema["multipleOf"]); ok && multipleOf.Sign() > 0 {
+		if !new(big.Rat).Quo(n, multipleOf).IsInt() {
+			v.fail(path, "multipleOf", "must be a multiple of %s", multipleOf.FloatString(precision(multipleOf)))
+		}
+	}
+}
+
+func comparison(operator string, exclusive bool) string {
+	if exclusive {
+		return operator
+	}
+	return operator + "="
+}
+
+// precision is the number of decimal places needed to print a number from a schema
+func precision(r *big.Rat) int {
+	for p := 0; p < 20; p++ {
+		scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p)), nil)))
+		if scaled.IsInt() {
+			return p
+		}
+	}
+	return 20
+}
+
+func typeOf(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if n, ok := toRat(value); ok && n.IsInt() {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// toRat converts a number to an exact value. Numbers outside the limits are not converted.
+func toRat(value any) (*big.Rat, bool) {
+	n, ok := value.(json.Number)
+	if !ok || !withinLimits(n) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(n.String())
+}
+
+func withinLimits(n json.Number) bool {
+	s := n.String()
+	if len(s) > maxNumberLength {
+		return false
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exponent, err := strconv.Atoi(s[i+1:])
+		if err != nil || exponent < -maxExponent || exponent > maxExponent {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNumbers returns an error if a number in the value is outside the limits
+func checkNumbers(value any) error {
+	switch value := value.(type) {
+	case json.Number:
+		if !withinLimits(value) {
+			return errNumberTooLarge
+		}
+	case []any:
+		for _, item := range value {
+			if err := checkNumbers(item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, item := range value {
+			if err := checkNumbers(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// equal compares JSON values, treating numbers as equal if they have the same value
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		x, ok1 := toRat(a)
+		y, ok2 := toRat(b)
+		return ok1 && ok2 && x.Cmp(y) == 0
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equal)
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := b[k]
+			if !ok || !equal(va, vb) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// canonical returns a representation of a JSON value that is the same for values that are equal,
+// with numbers in their exact form and object keys in order
+func canonical(value any) string {
+	var b strings.Builder
+	writeCanonical(&b, value)
+	return b.String()
+}
+
+func writeCanonical(b *strings.Builder, value any) {
+	switch value := value.(type) {
+	case json.Number:
+		if n, ok := toRat(value); ok {
+			b.WriteString(n.RatString())
+		} else {
+			b.WriteString(value.String())
+		}
+	case []any:
+		b.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonical(b, item)
+		}
+		b.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(k))
+			b.WriteByte(':')
+			writeCanonical(b, value[k])
+		}
+		b.WriteByte('}')
+	default:
+		b.WriteString(marshal(value))
+	}
+}
+
+func marshal(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func escape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,304 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/brokerapi/v12/internal/jsonschema"
+)
+
+var _ = Describe("JSON Schema", func() {
+	validate := func(schema any, document string) []jsonschema.Violation {
+		compiled, err := jsonschema.Compile(schema)
+		Expect(err).NotTo(HaveOccurred())
+		violations, err := compiled.Validate([]byte(document))
+		Expect(err).NotTo(HaveOccurred())
+		return violations
+	}
+
+	keywords := func(violations []jsonschema.Violation) []string {
+		var result []string
+		for _, v := range violations {
+			result = append(result, v.Path+" "+v.Keyword)
+		}
+		return result
+	}
+
+	Describe("Compile()", func() {
+		It("rejects remote references", func() {
+			_, err := jsonschema.Compile(map[string]any{"$ref": "https://example.com/schema.json"})
+			Expect(err).To(MatchError(ContainSubstring("remote reference")))
+		})
+
+		It("rejects local references that cannot be resolved", func() {
+			_, err := jsonschema.Compile(map[string]any{"$ref": "#/definitions/missing"})
+			Expect(err).To(MatchError(ContainSubstring("cannot be resolved")))
+		})
+
+		It("rejects invalid patterns", func() {
+			_, err := jsonschema.Compile(map[string]any{"properties": map[string]any{"name": map[string]any{"pattern": "("}}})
+			Expect(err).To(MatchError(ContainSubstring("/properties/name/pattern")))
+		})
+
+		It("rejects malformed schemas", func() {
+			for schema, message := range map[string]string{
+				`{"type":"text"}`:                                     `/type: unknown type "text"`,
+				`{"properties":{"a":{"minLength":-1}}}`:               `/properties/a/minLength: must be a non-negative integer`,
+				`{"required":"name"}`:                                 `/required: must be an array`,
+				`{"anyOf":[]}`:                                        `/anyOf: must be a non-empty array`,
+				`{"items":[{"maximum":"10"}]}`:                        `/items/0/maximum: must be a number`,
+				`{"properties":[]}`:                                   `/properties: must be an object`,
+				`{"not":"string"}`:                                    `/not: schema must be an object or a boolean`,
+				`{"$defs":{"a":{"multipleOf":0}},"$ref":"#/$defs/a"}`: `/$defs/a/multipleOf: must be a number greater than 0`,
+			} {
+				var decoded any
+				Expect(json.Unmarshal([]byte(schema), &decoded)).To(Succeed())
+				_, err := jsonschema.Compile(decoded)
+				Expect(err).To(MatchError(message), schema)
+			}
+		})
+
+		It("does not treat property names as keywords", func() {
+			_, err := jsonschema.Compile(map[string]any{"properties": map[string]any{"pattern": map[string]any{"type": "string"}}})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not treat enum values as schemas", func() {
+			_, err := jsonschema.Compile(map[string]any{"enum": []any{map[string]any{"$ref": "http://example.com"}}})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Validate()", func() {
+		It("rejects a document that is not JSON", func() {
+			compiled, err := jsonschema.Compile(map[string]any{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = compiled.Validate([]byte("{"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("checks types", func() {
+			schema := map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"count":    map[string]any{"type": "integer"},
+					"ratio":    map[string]any{"type": "number"},
+					"name":     map[string]any{"type": []any{"string", "null"}},
+					"enabled":  map[string]any{"type": "boolean"},
+					"tags":     map[string]any{"type": "array"},
+					"settings": map[string]any{"type": "object"},
+				},
+			}
+
+			Expect(validate(schema, `{"count":1.0,"ratio":1,"name":null,"enabled":true,"tags":[],"settings":{}}`)).To(BeEmpty())
+
+			violations := validate(schema, `{"count":1.5,"ratio":"1","name":2,"enabled":"yes","tags":{},"settings":[]}`)
+			Expect(keywords(violations)).To(Equal([]string{
+				"/count type", "/enabled type", "/name type", "/ratio type", "/settings type", "/tags type",
+			}))
+			Expect(violations[0].Message).To(Equal("must be integer, but is number"))
+			Expect(violations[2].Message).To(Equal("must be string or null, but is integer"))
+		})
+
+		It("checks required and additional properties", func() {
+			schema := map[string]any{
+				"required":             []string{"name"},
+				"properties":           map[string]any{"name": map[string]any{}},
+				"patternProperties":    map[string]any{"^x-": map[string]any{"type": "string"}},
+				"additionalProperties": false,
+			}
+
+			Expect(validate(schema, `{"name":"a","x-extra":"b"}`)).To(BeEmpty())
+
+			violations := validate(schema, `{"x-extra":1,"other":true}`)
+			Expect(keywords(violations)).To(Equal([]string{" required", "/other additionalProperties", "/x-extra type"}))
+			Expect(violations[0].Message).To(Equal(`missing required property "name"`))
+		})
+
+		It("validates additional properties against a schema", func() {
+			schema := map[string]any{"additionalProperties": map[string]any{"type": "integer"}}
+			Expect(keywords(validate(schema, `{"a":1,"b":"2"}`))).To(Equal([]string{"/b type"}))
+		})
+
+		It("checks the number of properties and property names", func() {
+			schema := map[string]any{"minProperties": 2, "maxProperties": 3, "propertyNames": map[string]any{"maxLength": 3}}
+			Expect(keywords(validate(schema, `{"long":1}`))).To(Equal([]string{" minProperties", "/long propertyNames"}))
+			Expect(keywords(validate(schema, `{"a":1,"b":2,"c":3,"d":4}`))).To(Equal([]string{" maxProperties"}))
+		})
+
+		It("checks dependencies", func() {
+			schema := map[string]any{
+				"dependentRequired": map[string]any{"username": []any{"password"}},
+				"dependencies":      map[string]any{"port": map[string]any{"required": []any{"host"}}},
+			}
+
+			Expect(validate(schema, `{"username":"u","password":"p","port":1,"host":"h"}`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `{"username":"u"}`))).To(Equal([]string{" dependentRequired"}))
+			Expect(keywords(validate(schema, `{"port":1}`))).To(Equal([]string{" required"}))
+		})
+
+		It("checks strings", func() {
+			schema := map[string]any{"minLength": 2, "maxLength": 3, "pattern": "^[a-zé]+$"}
+
+			Expect(validate(schema, `"éé"`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `"a"`))).To(Equal([]string{" minLength"}))
+			Expect(keywords(validate(schema, `"abcd"`))).To(Equal([]string{" maxLength"}))
+			Expect(validate(schema, `"AB"`)[0].Message).To(Equal(`must match the pattern "^[a-zé]+$"`))
+		})
+
+		It("checks numbers", func() {
+			schema := map[string]any{"minimum": 1, "maximum": 10, "multipleOf": 0.5}
+
+			Expect(validate(schema, `2.5`)).To(BeEmpty())
+			Expect(validate(schema, `0`)[0].Message).To(Equal("must be >= 1"))
+			Expect(validate(schema, `11`)[0].Message).To(Equal("must be <= 10"))
+			Expect(validate(schema, `2.25`)[0].Message).To(Equal("must be a multiple of 0.5"))
+		})
+
+		It("supports both forms of exclusive bounds", func() {
+			draft4 := map[string]any{"minimum": 1, "exclusiveMinimum": true, "maximum": 10, "exclusiveMaximum": true}
+			Expect(keywords(validate(draft4, `1`))).To(Equal([]string{" minimum"}))
+			Expect(keywords(validate(draft4, `10`))).To(Equal([]string{" maximum"}))
+			Expect(validate(draft4, `5`)).To(BeEmpty())
+
+			later := map[string]any{"exclusiveMinimum": 1, "exclusiveMaximum": 10}
+			Expect(keywords(validate(later, `1`))).To(Equal([]string{" exclusiveMinimum"}))
+			Expect(keywords(validate(later, `10`))).To(Equal([]string{" exclusiveMaximum"}))
+			Expect(validate(later, `5`)).To(BeEmpty())
+		})
+
+		It("compares large integers exactly", func() {
+			schema := map[string]any{"maximum": 9007199254740992}
+			Expect(keywords(validate(schema, `9007199254740993`))).To(Equal([]string{" maximum"}))
+		})
+
+		It("rejects numbers that are too large to compare", func() {
+			compiled, err := jsonschema.Compile(map[string]any{"items": map[string]any{"maximum": 10}})
+			Expect(err).NotTo(HaveOccurred())
+			for _, document := range []string{`[1e1000000]`, `[1e-1001]`, `{"a":[1` + strings.Repeat("0", 1000) + `]}`} {
+				_, err = compiled.Validate([]byte(document))
+				Expect(err).To(MatchError(ContainSubstring("numbers must have at most 1000 characters")), document)
+			}
+			Expect(validate(map[string]any{"minimum": 0}, `1e1000`)).To(BeEmpty())
+		})
+
+		It("checks arrays", func() {
+			schema := map[string]any{"minItems": 1, "maxItems": 3, "uniqueItems": true, "items": map[string]any{"type": "integer"}}
+
+			Expect(validate(schema, `[1,2]`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `[]`))).To(Equal([]string{" minItems"}))
+			Expect(keywords(validate(schema, `[1,2,3,4]`))).To(Equal([]string{" maxItems"}))
+			Expect(keywords(validate(schema, `[1,1.0]`))).To(Equal([]string{" uniqueItems"}))
+			Expect(keywords(validate(schema, `[1,"2"]`))).To(Equal([]string{"/1 type"}))
+		})
+
+		It("checks that the items of large arrays are unique", func() {
+			items := make([]string, 0, 20001)
+			for i := range 20000 {
+				items = append(items, fmt.Sprintf(`{"a":%d,"b":[%d.5,"x"]}`, i, i))
+			}
+			document := "[" + strings.Join(items, ",")
+
+			Expect(validate(map[string]any{"uniqueItems": true}, document+"]")).To(BeEmpty())
+			Expect(validate(map[string]any{"uniqueItems": true}, document+`,{"b":[1.50,"x"],"a":1}]`)).To(Equal([]jsonschema.Violation{
+				{Path: "", Keyword: "uniqueItems", Message: "items 1 and 20000 are equal"},
+			}))
+		})
+
+		It("supports tuples in both draft-04 and 2020-12 form", func() {
+			draft4 := map[string]any{"items": []any{map[string]any{"type": "string"}}, "additionalItems": false}
+			Expect(keywords(validate(draft4, `[1,2]`))).To(Equal([]string{"/0 type", "/1 false"}))
+
+			later := map[string]any{"prefixItems": []any{map[string]any{"type": "string"}}, "items": map[string]any{"type": "integer"}}
+			Expect(validate(later, `["a",1]`)).To(BeEmpty())
+			Expect(keywords(validate(later, `[1,"b"]`))).To(Equal([]string{"/0 type", "/1 type"}))
+		})
+
+		It("checks contains", func() {
+			schema := map[string]any{"contains": map[string]any{"const": "x"}, "maxContains": 1}
+			Expect(validate(schema, `["a","x"]`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `["a"]`))).To(Equal([]string{" contains"}))
+			Expect(keywords(validate(schema, `["x","x"]`))).To(Equal([]string{" maxContains"}))
+		})
+
+		It("checks enum and const", func() {
+			Expect(validate(map[string]any{"enum": []any{"a", 1, map[string]any{"b": 2}}}, `{"b":2.0}`)).To(BeEmpty())
+			Expect(validate(map[string]any{"enum": []any{"a", 1}}, `"c"`)[0].Message).To(Equal(`must be one of ["a",1]`))
+			Expect(validate(map[string]any{"const": "a"}, `"b"`)[0].Message).To(Equal(`must be "a"`))
+		})
+
+		It("supports combinators", func() {
+			schema := map[string]any{
+				"allOf": []any{map[string]any{"type": "integer"}},
+				"anyOf": []any{map[string]any{"minimum": 10}, map[string]any{"maximum": 0}},
+				"oneOf": []any{map[string]any{"multipleOf": 2}, map[string]any{"multipleOf": 3}},
+				"not":   map[string]any{"const": 12},
+			}
+
+			Expect(validate(schema, `10`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `5`))).To(ConsistOf(" anyOf", " oneOf"))
+			Expect(keywords(validate(schema, `12`))).To(ConsistOf(" oneOf", " not"))
+			Expect(keywords(validate(schema, `"a"`))).To(ConsistOf(" type", " oneOf"))
+		})
+
+		It("supports conditionals", func() {
+			schema := map[string]any{
+				"if":   map[string]any{"properties": map[string]any{"tls": map[string]any{"const": true}}},
+				"then": map[string]any{"required": []any{"certificate"}},
+				"else": map[string]any{"properties": map[string]any{"certificate": false}},
+			}
+
+			Expect(validate(schema, `{"tls":true,"certificate":"c"}`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `{"tls":true}`))).To(Equal([]string{" required"}))
+			Expect(keywords(validate(schema, `{"tls":false,"certificate":"c"}`))).To(Equal([]string{"/certificate false"}))
+		})
+
+		It("resolves local references", func() {
+			schema := map[string]any{
+				"definitions": map[string]any{"size": map[string]any{"enum": []any{"small", "large"}}},
+				"$defs":       map[string]any{"node": map[string]any{"properties": map[string]any{"child": map[string]any{"$ref": "#/$defs/node"}, "size": map[string]any{"$ref": "#/definitions/size"}}}},
+				"$ref":        "#/$defs/node",
+			}
+
+			Expect(validate(schema, `{"size":"small","child":{"size":"large"}}`)).To(BeEmpty())
+			Expect(keywords(validate(schema, `{"child":{"child":{"size":"medium"}}}`))).To(Equal([]string{"/child/child/size enum"}))
+		})
+
+		It("stops following recursive references", func() {
+			schema := map[string]any{"$defs": map[string]any{"loop": map[string]any{"$ref": "#/$defs/loop"}}, "$ref": "#/$defs/loop"}
+			Expect(keywords(validate(schema, `{}`))).To(Equal([]string{" $ref"}))
+		})
+
+		It("follows each reference once for a value, however many branches refer to it", func() {
+			schema := map[string]any{"anyOf": []any{map[string]any{"$ref": "#"}, map[string]any{"$ref": "#"}}}
+			Expect(keywords(validate(schema, `{}`))).To(Equal([]string{" anyOf"}))
+		})
+
+		It("checks property names separately from their values", func() {
+			schema := map[string]any{
+				"$defs":         map[string]any{"short": map[string]any{"maxLength": 3}},
+				"properties":    map[string]any{"name": map[string]any{"$ref": "#/$defs/short"}},
+				"propertyNames": map[string]any{"$ref": "#/$defs/short"},
+			}
+			Expect(keywords(validate(schema, `{"name":"abc"}`))).To(Equal([]string{"/name propertyNames"}))
+		})
+
+		It("escapes property names in paths", func() {
+			schema := map[string]any{"additionalProperties": false}
+			Expect(keywords(validate(schema, `{"a/b~c":1}`))).To(Equal([]string{"/a~1b~0c additionalProperties"}))
+		})
+
+		It("supports boolean schemas", func() {
+			Expect(validate(true, `1`)).To(BeEmpty())
+			Expect(keywords(validate(false, `1`))).To(Equal([]string{" false"}))
+		})
+
+		It("ignores unknown keywords and formats", func() {
+			Expect(validate(map[string]any{"$schema": "http://json-schema.org/draft-04/schema#", "format": "email", "title": "x"}, `"nope"`)).To(BeEmpty())
+		})
+	})
+})
@@ -7,4 +7,5 @@ const (
 	InfoLocationKey        ContextKey = "infoLocation"
 	OriginatingIdentityKey ContextKey = "originatingIdentity"
 	RequestIdentityKey     ContextKey = "requestIdentity"
+	PrincipalKey           ContextKey = "principal"
 )
@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+const (
+	IDInvalidKey = "id-invalid"
+
+	idPolicyLogKey = "id-policy-check"
+)
+
+// IDPolicy restricts the instance and binding IDs that platforms may use, for brokers that use the IDs
+// as database keys or resource names. The zero IDPolicy allows any ID.
+type IDPolicy struct {
+	// MaxLength is the maximum number of characters in an ID, or no limit if it is zero
+	MaxLength int
+
+	// AllowedCharacters reports whether a character may be used in an ID. Any character is allowed if it is nil.
+	AllowedCharacters func(r rune) bool
+
+	// UUIDOnly requires IDs to be UUIDs in the canonical form, such as "1f7cd5ab-5ba1-4e8e-a2ad-5e5e5d4f20c3"
+	UUIDOnly bool
+}
+
+// Check returns an error describing why the ID does not conform to the policy. The name of the ID
+// is used in the error message.
+func (p IDPolicy) Check(name, id string) error {
+	if p.MaxLength > 0 && utf8.RuneCountInString(id) > p.MaxLength {
+		return fmt.Errorf("%s must not be longer than %d characters", name, p.MaxLength)
+	}
+
+	if p.AllowedCharacters != nil {
+		for _, r := range id {
+			if !p.AllowedCharacters(r) {
+				return fmt.Errorf("%s must not contain the character %q", name, r)
+			}
+		}
+	}
+
+	if p.UUIDOnly {
+		if _, err := uuid.Parse(id); err != nil || len(id) != 36 {
+			return fmt.Errorf("%s must be a UUID", name)
+		}
+	}
+
+	return nil
+}
+
+type IDPolicyMiddleware struct {
+	Logger *slog.Logger
+	Policy IDPolicy
+}
+
+// ValidateIDs rejects requests whose instance_id or binding_id path values do not conform to the policy.
+// It must wrap a handler registered with a pattern, so that the path values have been set.
+func (m IDPolicyMiddleware) ValidateIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, name := range []string{"instance_id", "binding_id"} {
+			id := req.PathValue(name)
+			if id == "" {
+				continue
+			}
+
+			if err := m.Policy.Check(name, id); err != nil {
+				m.Logger.Error(fmt.Sprintf("%s.%s", idPolicyLogKey, IDInvalidKey), slog.Any("error", err))
+
+				w.Header().Set("Content-type", "application/json")
+				setBrokerRequestIdentityHeader(req, w)
+
+				statusResponse := http.StatusBadRequest
+				w.WriteHeader(statusResponse)
+				errorResp := apiresponses.ErrorResponse{
+					Description: err.Error(),
+				}
+				if err := json.NewEncoder(w).Encode(errorResp); err != nil {
+					m.Logger.Error(fmt.Sprintf("%s.%s", idPolicyLogKey, "encoding response"), slog.Any("error", err), slog.Int("status", statusResponse), slog.Any("response", errorResp))
+				}
+
+				return
+			}
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -17,13 +17,67 @@ package middlewares
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/utils"
+)
+
+const (
+	OriginatingIdentityInvalidKey = "originating-identity-invalid"
+
+	originatingIdentityLogKey = "originating-identity-header-check"
 )
 
+// AddOriginatingIdentityToContext adds the unparsed X-Broker-API-Originating-Identity header to the
+// context under OriginatingIdentityKey. If the header can be parsed, the domain.OriginatingIdentity
+// is also added, and can be retrieved with utils.RetrieveOriginatingIdentityFromContext().
 func AddOriginatingIdentityToContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		originatingIdentity := req.Header.Get("X-Broker-API-Originating-Identity")
 		newCtx := context.WithValue(req.Context(), OriginatingIdentityKey, originatingIdentity)
+		if parsed, err := domain.ParseOriginatingIdentity(originatingIdentity); err == nil {
+			newCtx = utils.AddOriginatingIdentityToContext(newCtx, parsed)
+		}
 		next.ServeHTTP(w, req.WithContext(newCtx))
 	})
 }
+
+type OriginatingIdentityMiddleware struct {
+	Logger *slog.Logger
+}
+
+// ValidateOriginatingIdentityHdr rejects requests with a malformed X-Broker-API-Originating-Identity header.
+// The header is optional, so requests without it are accepted.
+func (m OriginatingIdentityMiddleware) ValidateOriginatingIdentityHdr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		originatingIdentity := req.Header.Get("X-Broker-API-Originating-Identity")
+		if originatingIdentity == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if _, err := domain.ParseOriginatingIdentity(originatingIdentity); err != nil {
+			m.Logger.Error(fmt.Sprintf("%s.%s", originatingIdentityLogKey, OriginatingIdentityInvalidKey), slog.Any("error", err))
+
+			w.Header().Set("Content-type", "application/json")
+			setBrokerRequestIdentityHeader(req, w)
+
+			statusResponse := http.StatusBadRequest
+			w.WriteHeader(statusResponse)
+			errorResp := ErrorResponse{
+				Description: err.Error(),
+			}
+			if err := json.NewEncoder(w).Encode(errorResp); err != nil {
+				m.Logger.Error(fmt.Sprintf("%s.%s", originatingIdentityLogKey, "encoding response"), slog.Any("error", err), slog.Int("status", statusResponse), slog.Any("response", errorResp))
+			}
+
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
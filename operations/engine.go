@@ -0,0 +1,480 @@
+// Package operations runs the jobs of asynchronous operations returned by a ServiceBroker, and tracks
+// their state so that last_operation requests can be answered without calling the ServiceBroker.
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+)
+
+const (
+	defaultWorkers   = 10
+	defaultQueueSize = 100
+	defaultRetention = 24 * time.Hour
+
+	// Finished operations are pruned as often as the retention period, but at most once a second
+	// and at least once an hour
+	minPruneInterval = time.Second
+	maxPruneInterval = time.Hour
+
+	cancelledDescription   = "operation was cancelled"
+	interruptedDescription = "operation was interrupted by a restart of the service broker"
+)
+
+// ErrShutdown is returned when an operation is started after the Engine has been shut down
+var ErrShutdown = errors.New("operation engine has been shut down")
+
+// Config configures an Engine. The zero Config uses the defaults.
+type Config struct {
+	// Workers is the number of jobs that can run at the same time. The default is 10.
+	Workers int
+
+	// QueueSize is the number of jobs that can wait for a worker. When the queue is full, new operations
+	// are rejected with apiresponses.ErrOperationQueueFull. The default is 100.
+	QueueSize int
+
+	// Retention is how long finished operations are kept so that the platform can poll them. They are deleted
+	// in the background, up to an hour after the retention period. The default is 24 hours.
+	Retention time.Duration
+
+	// Logger logs the outcome of operations. The default is slog.Default().
+	Logger *slog.Logger
+
+	// Store records the state of operations. The default is a MemoryStore. Use a FileStore so that
+	// last operation requests can be answered after the broker restarts.
+	Store OperationStore
+
+	// Recover is called by NewEngine for each operation in the Store that was in progress when the
+	// broker stopped. By default, these operations fail.
+	Recover RecoverFunc
+}
+
+// RecoverFunc decides what happens to an operation that was interrupted when the broker stopped. It can
+// return a job that resumes the operation, which keeps its ID. If it returns no job, the operation fails,
+// with the error as its description if there is one.
+type RecoverFunc func(operation Operation) (domain.OperationJob, error)
+
+// Operation is the state of an asynchronous operation. Its ID is the operation data returned to the platform.
+type Operation struct {
+	ID          string                    `json:"id"`
+	Type        domain.AsyncOperation     `json:"type"`
+	InstanceID  string                    `json:"instance_id"`
+	BindingID   string                    `json:"binding_id,omitempty"`
+	State       domain.LastOperationState `json:"state"`
+	Description string                    `json:"description,omitempty"`
+	StartedAt   time.Time                 `json:"started_at"`
+	UpdatedAt   time.Time                 `json:"updated_at"`
+
+	// InstanceUsable and UpdateRepeatable are set when the job failed with an apiresponses.FailureResponse
+	// that has them
+	InstanceUsable   *bool `json:"instance_usable,omitempty"`
+	UpdateRepeatable *bool `json:"update_repeatable,omitempty"`
+}
+
+// LastOperation returns the state of the operation as reported to the platform
+func (o Operation) LastOperation() domain.LastOperation {
+	return domain.LastOperation{
+		State:            o.State,
+		Description:      o.Description,
+		InstanceUsable:   o.InstanceUsable,
+		UpdateRepeatable: o.UpdateRepeatable,
+	}
+}
+
+// conflicts reports whether the operations cannot run at the same time. Operations on an instance
+// conflict with all operations on the instance and its bindings, and operations on a binding
+// conflict with other operations on the same binding.
+func (o Operation) conflicts(other Operation) bool {
+	return o.InstanceID == other.InstanceID && (o.BindingID == "" || other.BindingID == "" || o.BindingID == other.BindingID)
+}
+
+// Engine runs the jobs of asynchronous operations on a bounded pool of workers. Each job has a context
+// that is cancelled when the operation is cancelled or the Engine is shut down.
+type Engine struct {
+	config Config
+	now    func() time.Time
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	queue   chan *operation
+	workers sync.WaitGroup
+
+	// lock protects the operations in progress. Changes to them are saved in the Store without
+	// holding it, so that Get does not wait for the Store.
+	lock    sync.Mutex
+	running map[string]*operation
+}
+
+type operation struct {
+	Operation
+	job    domain.OperationJob
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// saving orders the changes of the operation in the Store
+	saving sync.Mutex
+}
+
+// NewEngine creates an Engine, recovers the operations that were interrupted when the broker stopped,
+// and starts the workers. Call Shutdown() to stop them.
+func NewEngine(config Config) (*Engine, error) {
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultRetention
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Engine{
+		config:  config,
+		now:     time.Now,
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]*operation),
+	}
+
+	resumed, err := e.recover()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	e.queue = make(chan *operation, config.QueueSize+len(resumed))
+	for _, op := range resumed {
+		e.queue <- op
+	}
+
+	e.workers.Add(config.Workers)
+	for range config.Workers {
+		go e.work()
+	}
+	go e.pruneEvery(min(max(config.Retention, minPruneInterval), maxPruneInterval))
+
+	return e, nil
+}
+
+// Start queues the job of an operation on an instance, or on a binding if bindingID is not empty. It returns
+// apiresponses.ErrConcurrencyError if a conflicting operation is in progress, and apiresponses.ErrOperationQueueFull
+// if the job cannot be queued.
+func (e *Engine) Start(operationType domain.AsyncOperation, instanceID, bindingID string, job domain.OperationJob) (Operation, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.ctx.Err() != nil {
+		return Operation{}, ErrShutdown
+	}
+
+	now := e.now()
+	op := e.newOperation(Operation{
+		ID:         uuid.NewString(),
+		Type:       operationType,
+		InstanceID: instanceID,
+		BindingID:  bindingID,
+		State:      domain.InProgress,
+		StartedAt:  now,
+		UpdatedAt:  now,
+	}, job)
+
+	for _, existing := range e.running {
+		if existing.conflicts(op.Operation) {
+			op.cancel()
+			return Operation{}, apiresponses.ErrConcurrencyError
+		}
+	}
+
+	if len(e.queue) == cap(e.queue) {
+		op.cancel()
+		return Operation{}, apiresponses.ErrOperationQueueFull
+	}
+
+	if err := e.config.Store.Save(op.Operation); err != nil {
+		op.cancel()
+		return Operation{}, err
+	}
+
+	e.running[op.ID] = op
+	e.queue <- op
+
+	return op.Operation, nil
+}
+
+// Get returns the operation with the ID, or false if there is none
+func (e *Engine) Get(id string) (Operation, bool, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if op, ok := e.running[id]; ok {
+		return op.Operation, true, nil
+	}
+	return e.config.Store.Get(id)
+}
+
+// Lookup returns the operation with the ID if it is for the instance and binding, or false if there is none.
+// The bindingID is empty for operations on instances.
+func (e *Engine) Lookup(instanceID, bindingID, id string) (Operation, bool, error) {
+	op, ok, err := e.Get(id)
+	if err != nil || !ok || op.InstanceID != instanceID || op.BindingID != bindingID {
+		return Operation{}, false, err
+	}
+	return op, true, nil
+}
+
+// Cancel cancels the context of the job of an operation that is in progress, and returns false if there is none.
+// A job that has not started will not be run, and fails. A job that has started decides the outcome of the operation.
+func (e *Engine) Cancel(id string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	op, ok := e.running[id]
+	if !ok {
+		return false
+	}
+	op.cancel()
+	return true
+}
+
+// Shutdown cancels the contexts of all jobs, and waits for the running jobs to return or for ctx to be done.
+// Operations that are interrupted remain in progress in the Store, and are recovered by the next Engine.
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+	e.cancel()
+	e.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Engine) newOperation(state Operation, job domain.OperationJob) *operation {
+	ctx, cancel := context.WithCancel(e.ctx)
+	return &operation{Operation: state, job: job, ctx: ctx, cancel: cancel}
+}
+
+// recover calls the RecoverFunc for each operation in the Store that is in progress, and returns the
+// operations to resume
+func (e *Engine) recover() ([]*operation, error) {
+	interrupted, err := e.config.Store.List()
+	if err != nil {
+		return nil, fmt.Errorf("error recovering operations: %w", err)
+	}
+
+	var resumed []*operation
+	for _, state := range interrupted {
+		if state.State != domain.InProgress {
+			continue
+		}
+
+		var job domain.OperationJob
+		description := interruptedDescription
+		if e.config.Recover != nil {
+			job, err = e.config.Recover(state)
+			if err != nil {
+				description = err.Error()
+			}
+		}
+
+		logger := e.logger(state)
+		if job != nil {
+			logger.Info("operations.resumed")
+			op := e.newOperation(state, job)
+			e.running[op.ID] = op
+			resumed = append(resumed, op)
+			continue
+		}
+
+		logger.Error("operations.interrupted", slog.String("error", description))
+		state.State = domain.Failed
+		state.Description = description
+		state.UpdatedAt = e.now()
+		if err := e.config.Store.Save(state); err != nil {
+			return nil, fmt.Errorf("error recovering operations: %w", err)
+		}
+	}
+
+	return resumed, nil
+}
+
+// pruneEvery prunes finished operations when the Engine starts and then at the interval, until it is shut down
+func (e *Engine) pruneEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.prune(e.now())
+
+		select {
+		case <-ticker.C:
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// prune deletes the finished operations that are older than the retention period. Finished operations
+// do not change, so the lock is not needed.
+func (e *Engine) prune(now time.Time) {
+	finished, err := e.config.Store.List()
+	if err != nil {
+		e.config.Logger.Error("operations.prune-failed", slog.Any("error", err))
+		return
+	}
+
+	for _, op := range finished {
+		if op.State != domain.InProgress && now.Sub(op.UpdatedAt) > e.config.Retention {
+			if err := e.config.Store.Delete(op.ID); err != nil {
+				e.config.Logger.Error("operations.prune-failed", slog.Any("error", err))
+				return
+			}
+		}
+	}
+}
+
+func (e *Engine) logger(op Operation) *slog.Logger {
+	return e.config.Logger.With(
+		slog.String("operation-id", op.ID),
+		slog.String("operation-type", string(op.Type)),
+		slog.String("instance-id", op.InstanceID),
+	)
+}
+
+func (e *Engine) work() {
+	defer e.workers.Done()
+
+	for {
+		select {
+		case op := <-e.queue:
+			e.run(op)
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Engine) run(op *operation) {
+	defer op.cancel()
+
+	logger := e.logger(op.Operation)
+
+	if op.ctx.Err() != nil {
+		if e.ctx.Err() == nil {
+			e.finish(op, domain.LastOperation{State: domain.Failed, Description: cancelledDescription})
+		}
+		return
+	}
+
+	err := runJob(op.ctx, op.job, func(description string) {
+		e.update(op, description)
+	})
+
+	switch {
+	case e.ctx.Err() != nil:
+		logger.Info("operations.interrupted", slog.Any("error", err))
+	case err != nil:
+		logger.Error("operations.failed", slog.Any("error", err))
+		e.finish(op, failure(err))
+	default:
+		logger.Info("operations.succeeded")
+		e.finish(op, domain.LastOperation{State: domain.Succeeded})
+	}
+}
+
+// update changes the description of an operation that is in progress
+func (e *Engine) update(op *operation, description string) {
+	op.saving.Lock()
+	defer op.saving.Unlock()
+
+	e.lock.Lock()
+	if _, ok := e.running[op.ID]; !ok {
+		e.lock.Unlock()
+		return
+	}
+	op.Description = description
+	op.UpdatedAt = e.now()
+	state := op.Operation
+	e.lock.Unlock()
+
+	e.save(state)
+}
+
+// finish sets the final state of an operation. An empty description keeps the last progress description.
+// The operation is reported from memory until its final state has been saved.
+func (e *Engine) finish(op *operation, final domain.LastOperation) {
+	op.saving.Lock()
+	defer op.saving.Unlock()
+
+	e.lock.Lock()
+	op.State = final.State
+	if final.Description != "" {
+		op.Description = final.Description
+	}
+	op.InstanceUsable = final.InstanceUsable
+	op.UpdateRepeatable = final.UpdateRepeatable
+	op.UpdatedAt = e.now()
+	state := op.Operation
+	e.lock.Unlock()
+
+	e.save(state)
+
+	e.lock.Lock()
+	delete(e.running, op.ID)
+	e.lock.Unlock()
+}
+
+// failure returns the final state of an operation whose job returned err. A FailureResponse can tell the
+// platform whether the instance is still usable and whether the update can be repeated.
+func failure(err error) domain.LastOperation {
+	final := domain.LastOperation{State: domain.Failed, Description: err.Error()}
+
+	var failureResponse *apiresponses.FailureResponse
+	if errors.As(err, &failureResponse) {
+		if response, ok := failureResponse.ErrorResponse().(apiresponses.ErrorResponse); ok {
+			final.InstanceUsable = response.InstanceUsable
+			final.UpdateRepeatable = response.UpdateRepeatable
+		}
+	}
+	return final
+}
+
+// save records the state of an operation, logging any error because the job cannot do anything about it
+func (e *Engine) save(state Operation) {
+	if err := e.config.Store.Save(state); err != nil {
+		e.logger(state).Error("operations.save-failed", slog.Any("error", err))
+	}
+}
+
+// runJob runs a job, turning a panic into an error
+func runJob(ctx context.Context, job domain.OperationJob, progress func(string)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("operation panicked: %v", r)
+		}
+	}()
+	return job(ctx, progress)
+}
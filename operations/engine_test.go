@@ -0,0 +1,313 @@
+package operations_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+var _ = Describe("Engine", func() {
+	var engine *operations.Engine
+
+	// blockingJob returns a job that reports progress and then waits until it is released or cancelled
+	blockingJob := func(release <-chan error) domain.OperationJob {
+		return func(ctx context.Context, progress func(string)) error {
+			progress("working")
+			select {
+			case err := <-release:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	state := func(id string) func() domain.LastOperationState {
+		return func() domain.LastOperationState {
+			operation, _, _ := engine.Get(id)
+			return operation.State
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		engine, err = operations.NewEngine(operations.Config{
+			Workers:   1,
+			QueueSize: 1,
+			Logger:    slog.New(slog.NewJSONHandler(GinkgoWriter, nil)),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(engine.Shutdown(context.Background())).To(Succeed())
+	})
+
+	It("runs a job to success", func() {
+		release := make(chan error)
+		operation, err := engine.Start(domain.AsyncProvision, "an-instance", "", blockingJob(release))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(operation.ID).NotTo(BeEmpty())
+		Expect(operation.Type).To(Equal(domain.AsyncProvision))
+		Expect(operation.State).To(Equal(domain.InProgress))
+
+		Eventually(func() string {
+			operation, _, _ := engine.Get(operation.ID)
+			return operation.Description
+		}).Should(Equal("working"))
+
+		release <- nil
+		Eventually(state(operation.ID)).Should(Equal(domain.Succeeded))
+
+		finished, ok, _ := engine.Lookup("an-instance", "", operation.ID)
+		Expect(ok).To(BeTrue())
+		Expect(finished.LastOperation()).To(Equal(domain.LastOperation{State: domain.Succeeded, Description: "working"}))
+	})
+
+	It("fails an operation when the job returns an error", func() {
+		operation, err := engine.Start(domain.AsyncBind, "an-instance", "a-binding", func(context.Context, func(string)) error {
+			return errors.New("no more credentials")
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(state(operation.ID)).Should(Equal(domain.Failed))
+		finished, _, _ := engine.Get(operation.ID)
+		Expect(finished.Description).To(Equal("no more credentials"))
+	})
+
+	It("reports whether the instance is usable when the job fails with a FailureResponse", func() {
+		failure := apiresponses.NewFailureResponseBuilder(errors.New("disk is full"), http.StatusUnprocessableEntity, "update-failed").
+			WithInstanceUsable(true).
+			WithUpdateRepeatable(false).
+			Build()
+		operation, err := engine.Start(domain.AsyncUpdate, "an-instance", "", func(context.Context, func(string)) error {
+			return failure
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(state(operation.ID)).Should(Equal(domain.Failed))
+		finished, _, _ := engine.Get(operation.ID)
+		Expect(finished.LastOperation()).To(Equal(domain.LastOperation{
+			State:            domain.Failed,
+			Description:      "disk is full",
+			InstanceUsable:   domain.InstanceUsableValue(true),
+			UpdateRepeatable: domain.UpdateRepeatableValue(false),
+		}))
+	})
+
+	It("fails an operation when the job panics", func() {
+		operation, err := engine.Start(domain.AsyncUnbind, "an-instance", "a-binding", func(context.Context, func(string)) error {
+			panic("oops")
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(state(operation.ID)).Should(Equal(domain.Failed))
+		finished, _, _ := engine.Get(operation.ID)
+		Expect(finished.Description).To(Equal("operation panicked: oops"))
+	})
+
+	It("rejects conflicting operations", func() {
+		release := make(chan error)
+		instance, err := engine.Start(domain.AsyncUpdate, "an-instance", "", blockingJob(release))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = engine.Start(domain.AsyncBind, "an-instance", "a-binding", blockingJob(release))
+		Expect(err).To(Equal(apiresponses.ErrConcurrencyError))
+
+		release <- nil
+		Eventually(state(instance.ID)).Should(Equal(domain.Succeeded))
+
+		_, err = engine.Start(domain.AsyncBind, "an-instance", "a-binding", blockingJob(release))
+		Expect(err).NotTo(HaveOccurred())
+		close(release)
+	})
+
+	It("rejects operations when the queue is full", func() {
+		release := make(chan error)
+		defer close(release)
+
+		running, err := engine.Start(domain.AsyncProvision, "instance-1", "", blockingJob(release))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() string {
+			operation, _, _ := engine.Get(running.ID)
+			return operation.Description
+		}).Should(Equal("working"))
+
+		_, err = engine.Start(domain.AsyncProvision, "instance-2", "", blockingJob(release))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = engine.Start(domain.AsyncProvision, "instance-3", "", blockingJob(release))
+		Expect(err).To(Equal(apiresponses.ErrOperationQueueFull))
+	})
+
+	It("cancels the context of a job", func() {
+		operation, err := engine.Start(domain.AsyncDeprovision, "an-instance", "", blockingJob(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() string {
+			operation, _, _ := engine.Get(operation.ID)
+			return operation.Description
+		}).Should(Equal("working"))
+
+		Expect(engine.Cancel(operation.ID)).To(BeTrue())
+		Eventually(state(operation.ID)).Should(Equal(domain.Failed))
+		finished, _, _ := engine.Get(operation.ID)
+		Expect(finished.Description).To(Equal("context canceled"))
+
+		Expect(engine.Cancel(operation.ID)).To(BeFalse())
+	})
+
+	It("only finds operations for the instance and binding", func() {
+		operation, err := engine.Start(domain.AsyncBind, "an-instance", "a-binding", func(context.Context, func(string)) error { return nil })
+		Expect(err).NotTo(HaveOccurred())
+
+		_, ok, _ := engine.Lookup("an-instance", "", operation.ID)
+		Expect(ok).To(BeFalse())
+		_, ok, _ = engine.Lookup("other-instance", "a-binding", operation.ID)
+		Expect(ok).To(BeFalse())
+		_, ok, _ = engine.Lookup("an-instance", "a-binding", "other-operation")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("interrupts jobs when it is shut down", func() {
+		operation, err := engine.Start(domain.AsyncProvision, "an-instance", "", blockingJob(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() string {
+			operation, _, _ := engine.Get(operation.ID)
+			return operation.Description
+		}).Should(Equal("working"))
+
+		Expect(engine.Shutdown(context.Background())).To(Succeed())
+		Expect(state(operation.ID)()).To(Equal(domain.InProgress))
+
+		_, err = engine.Start(domain.AsyncProvision, "other-instance", "", blockingJob(nil))
+		Expect(err).To(Equal(operations.ErrShutdown))
+	})
+
+	It("reports an operation that is finishing while its state is being saved", func() {
+		store := &slowStore{MemoryStore: operations.NewMemoryStore(), release: make(chan struct{})}
+		slow, err := operations.NewEngine(operations.Config{Store: store, Logger: slog.New(slog.NewJSONHandler(GinkgoWriter, nil))})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(slow.Shutdown, context.Background())
+
+		operation, err := slow.Start(domain.AsyncProvision, "an-instance", "", func(context.Context, func(string)) error { return nil })
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() domain.LastOperationState {
+			operation, _, _ := slow.Get(operation.ID)
+			return operation.State
+		}).Should(Equal(domain.Succeeded))
+		saved, _, _ := store.Get(operation.ID)
+		Expect(saved.State).To(Equal(domain.InProgress))
+
+		close(store.release)
+		Eventually(func() domain.LastOperationState {
+			operation, _, _ := store.Get(operation.ID)
+			return operation.State
+		}).Should(Equal(domain.Succeeded))
+	})
+	Describe("recovery", func() {
+		var store *operations.MemoryStore
+
+		interrupted := operations.Operation{
+			ID:          "interrupted",
+			Type:        domain.AsyncProvision,
+			InstanceID:  "an-instance",
+			State:       domain.InProgress,
+			Description: "halfway",
+		}
+
+		recoverWith := func(recoverFunc operations.RecoverFunc) {
+			Expect(engine.Shutdown(context.Background())).To(Succeed())
+
+			var err error
+			engine, err = operations.NewEngine(operations.Config{
+				Store:   store,
+				Recover: recoverFunc,
+				Logger:  slog.New(slog.NewJSONHandler(GinkgoWriter, nil)),
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			store = operations.NewMemoryStore()
+			Expect(store.Save(interrupted)).To(Succeed())
+			Expect(store.Save(operations.Operation{ID: "finished", InstanceID: "other-instance", State: domain.Succeeded, UpdatedAt: time.Now()})).To(Succeed())
+		})
+
+		It("fails interrupted operations by default", func() {
+			recoverWith(nil)
+
+			operation, ok, err := engine.Get("interrupted")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(operation.LastOperation()).To(Equal(domain.LastOperation{
+				State:       domain.Failed,
+				Description: "operation was interrupted by a restart of the service broker",
+			}))
+			Expect(state("finished")()).To(Equal(domain.Succeeded))
+		})
+
+		It("fails an interrupted operation with the error from the recover function", func() {
+			recoverWith(func(operations.Operation) (domain.OperationJob, error) {
+				return nil, errors.New("cannot resume provisioning")
+			})
+
+			operation, _, _ := engine.Get("interrupted")
+			Expect(operation.State).To(Equal(domain.Failed))
+			Expect(operation.Description).To(Equal("cannot resume provisioning"))
+		})
+
+		It("resumes an interrupted operation with the job from the recover function", func() {
+			var recovered []operations.Operation
+			release := make(chan error)
+			recoverWith(func(operation operations.Operation) (domain.OperationJob, error) {
+				recovered = append(recovered, operation)
+				return blockingJob(release), nil
+			})
+			Expect(recovered).To(Equal([]operations.Operation{interrupted}))
+
+			_, err := engine.Start(domain.AsyncUpdate, "an-instance", "", blockingJob(release))
+			Expect(err).To(Equal(apiresponses.ErrConcurrencyError))
+
+			release <- nil
+			Eventually(state("interrupted")).Should(Equal(domain.Succeeded))
+
+			stored, _, err := store.Get("interrupted")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored.LastOperation()).To(Equal(domain.LastOperation{State: domain.Succeeded, Description: "working"}))
+		})
+
+		It("deletes finished operations after the retention period in the background", func() {
+			Expect(store.Save(operations.Operation{ID: "expired", InstanceID: "other-instance", State: domain.Failed, UpdatedAt: time.Now().Add(-48 * time.Hour)})).To(Succeed())
+			recoverWith(nil)
+
+			expired := func() bool {
+				_, ok, _ := store.Get("expired")
+				return ok
+			}
+			Eventually(expired).Should(BeFalse())
+			Expect(state("finished")()).To(Equal(domain.Succeeded))
+		})
+	})
+})
+
+// slowStore is a MemoryStore that saves finished operations only after it is released
+type slowStore struct {
+	*operations.MemoryStore
+	release chan struct{}
+}
+
+func (s *slowStore) Save(operation operations.Operation) error {
+	if operation.State != domain.InProgress {
+		<-s.release
+	}
+	return s.MemoryStore.Save(operation)
+}
@@ -0,0 +1,11 @@
+package operations
+
+import "os"
+
+// SetJournalWriter replaces the function that appends entries to the journal of the FileStore
+func SetJournalWriter(s *FileStore, write func(file *os.File, data []byte) (int, error)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.write = write
+}
@@ -0,0 +1,283 @@
+package operations
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// minCompactionEntries is the smallest journal that is compacted automatically
+const minCompactionEntries = 1000
+
+// FileStore is an OperationStore that survives a restart of the broker. Every change is appended
+// to a journal file, and the state is kept in memory. The journal is compacted by rewriting it
+// with only the current operations when it is opened, and when it has grown to more than twice
+// the size needed.
+type FileStore struct {
+	path   string
+	logger *slog.Logger
+	write  func(file *os.File, data []byte) (int, error)
+
+	lock       sync.Mutex
+	file       *os.File // nil if the journal could not be opened again after it was compacted
+	size       int64    // the end of the last complete entry in the journal
+	entries    int
+	operations map[string]Operation
+}
+
+type journalEntry struct {
+	Operation *Operation `json:"operation,omitempty"`
+	Deleted   string     `json:"deleted,omitempty"`
+}
+
+// FileStoreOption configures optional behaviour of a FileStore
+type FileStoreOption func(*FileStore)
+
+// WithCompactionLogger sets the logger for errors compacting the journal after a change has been saved.
+// These errors are not returned, because the change is already in the journal. The default is slog.Default().
+func WithCompactionLogger(logger *slog.Logger) FileStoreOption {
+	return func(s *FileStore) {
+		s.logger = logger
+	}
+}
+
+// NewFileStore opens the journal at the path, creating it if it does not exist. A partially written
+// entry at the end of the journal, as left by a crash, is ignored.
+func NewFileStore(path string, options ...FileStoreOption) (*FileStore, error) {
+	s := &FileStore{path: path, logger: slog.Default(), write: (*os.File).Write, operations: make(map[string]Operation)}
+	for _, option := range options {
+		option(s)
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Save(operation Operation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.append(journalEntry{Operation: &operation}); err != nil {
+		return err
+	}
+	s.operations[operation.ID] = operation
+	s.maybeCompact()
+	return nil
+}
+
+func (s *FileStore) Get(id string) (Operation, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	operation, ok := s.operations[id]
+	return operation, ok, nil
+}
+
+func (s *FileStore) List() ([]Operation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return sortedOperations(s.operations), nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.operations[id]; !ok {
+		return nil
+	}
+	if err := s.append(journalEntry{Deleted: id}); err != nil {
+		return err
+	}
+	delete(s.operations, id)
+	s.maybeCompact()
+	return nil
+}
+
+// Compact rewrites the journal with only the current operations
+func (s *FileStore) Compact() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.compact()
+}
+
+// Close closes the journal. The FileStore must not be used afterwards.
+func (s *FileStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+func (s *FileStore) load() error {
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("error reading operation journal: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				// the last entry was not completely written
+				break
+			}
+			return fmt.Errorf("error reading operation journal %q line %d: %w", s.path, i+1, err)
+		}
+
+		switch {
+		case entry.Operation != nil:
+			s.operations[entry.Operation.ID] = *entry.Operation
+		case entry.Deleted != "":
+			delete(s.operations, entry.Deleted)
+		}
+	}
+
+	return nil
+}
+
+func (s *FileStore) append(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.write(s.file, append(line, '\n'))
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// A partially written entry would stop the journal from being read when the store is opened again
+		if truncateErr := s.file.Truncate(s.size); truncateErr != nil {
+			err = errors.Join(err, truncateErr)
+		}
+		return fmt.Errorf("error writing operation journal: %w", err)
+	}
+
+	s.size += int64(n)
+	s.entries++
+	return nil
+}
+
+func (s *FileStore) maybeCompact() {
+	if s.entries < minCompactionEntries || s.entries <= 2*len(s.operations) {
+		return
+	}
+	if err := s.compact(); err != nil {
+		s.logger.Error("operations.compact-failed", slog.Any("error", err))
+	}
+}
+
+// compact writes the current operations to a new journal, and replaces the old journal with it
+func (s *FileStore) compact() error {
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error compacting operation journal: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if err := writeJournal(temp, sortedOperations(s.operations)); err != nil {
+		temp.Close()
+		return fmt.Errorf("error compacting operation journal: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error compacting operation journal: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		return fmt.Errorf("error compacting operation journal: %w", err)
+	}
+
+	// The old journal is no longer at the path, so entries must not be appended to it. If the new
+	// journal cannot be opened, the next append tries again.
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	s.entries = len(s.operations)
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("error compacting operation journal: %w", err)
+	}
+	return nil
+}
+
+// open opens the journal for appending
+func (s *FileStore) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening operation journal: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error opening operation journal: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// syncDir makes a rename in the directory durable. Directories cannot be synced on Windows.
+func syncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func writeJournal(file *os.File, operations []Operation) error {
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, operation := range operations {
+		if err := encoder.Encode(journalEntry{Operation: &operation}); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
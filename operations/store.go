@@ -0,0 +1,78 @@
+package operations
+
+import (
+	"sort"
+	"sync"
+)
+
+// OperationStore records the state of operations, so that an Engine can answer last operation requests
+// for them and recover the operations that were interrupted when the broker stopped. Implementations
+// must be safe for concurrent use.
+type OperationStore interface {
+	// Save creates or replaces the operation with the same ID
+	Save(operation Operation) error
+
+	// Get returns the operation with the ID, or false if there is none
+	Get(id string) (Operation, bool, error)
+
+	// List returns all the operations, in the order they were started
+	List() ([]Operation, error)
+
+	// Delete removes the operation with the ID, if there is one
+	Delete(id string) error
+}
+
+// MemoryStore is an OperationStore that does not survive a restart of the broker
+type MemoryStore struct {
+	lock       sync.Mutex
+	operations map[string]Operation
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{operations: make(map[string]Operation)}
+}
+
+func (s *MemoryStore) Save(operation Operation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.operations[operation.ID] = operation
+	return nil
+}
+
+func (s *MemoryStore) Get(id string) (Operation, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	operation, ok := s.operations[id]
+	return operation, ok, nil
+}
+
+func (s *MemoryStore) List() ([]Operation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return sortedOperations(s.operations), nil
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.operations, id)
+	return nil
+}
+
+func sortedOperations(operations map[string]Operation) []Operation {
+	result := make([]Operation, 0, len(operations))
+	for _, operation := range operations {
+		result = append(result, operation)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result
+}
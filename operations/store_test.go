@@ -0,0 +1,208 @@
+package operations_test
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/brokerapi/v12/domain"
+	"github.com/pivotal-cf/brokerapi/v12/operations"
+)
+
+var _ = Describe("OperationStore", func() {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	provision := operations.Operation{
+		ID:         "provision",
+		Type:       domain.AsyncProvision,
+		InstanceID: "an-instance",
+		State:      domain.InProgress,
+		StartedAt:  started,
+		UpdatedAt:  started,
+	}
+	bind := operations.Operation{
+		ID:          "bind",
+		Type:        domain.AsyncBind,
+		InstanceID:  "an-instance",
+		BindingID:   "a-binding",
+		State:       domain.Succeeded,
+		Description: "bound",
+		StartedAt:   started.Add(time.Minute),
+		UpdatedAt:   started.Add(2 * time.Minute),
+	}
+
+	behavesLikeAnOperationStore := func(newStore func() operations.OperationStore) {
+		var store operations.OperationStore
+
+		BeforeEach(func() {
+			store = newStore()
+		})
+
+		It("saves, lists and deletes operations", func() {
+			Expect(store.Save(bind)).To(Succeed())
+			Expect(store.Save(provision)).To(Succeed())
+
+			operation, ok, err := store.Get("bind")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(operation).To(Equal(bind))
+
+			Expect(store.List()).To(Equal([]operations.Operation{provision, bind}))
+
+			Expect(store.Delete("provision")).To(Succeed())
+			Expect(store.Delete("unknown")).To(Succeed())
+			_, ok, err = store.Get("provision")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(store.List()).To(Equal([]operations.Operation{bind}))
+		})
+
+		It("replaces an operation with the same ID", func() {
+			Expect(store.Save(provision)).To(Succeed())
+
+			succeeded := provision
+			succeeded.State = domain.Succeeded
+			Expect(store.Save(succeeded)).To(Succeed())
+
+			Expect(store.List()).To(Equal([]operations.Operation{succeeded}))
+		})
+	}
+
+	Describe("MemoryStore", func() {
+		behavesLikeAnOperationStore(func() operations.OperationStore {
+			return operations.NewMemoryStore()
+		})
+	})
+
+	Describe("FileStore", func() {
+		var path string
+
+		open := func() *operations.FileStore {
+			store, err := operations.NewFileStore(path)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() { store.Close() })
+			return store
+		}
+
+		journal := func() []string {
+			data, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			return strings.Split(strings.TrimSpace(string(data)), "\n")
+		}
+
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "operations.journal")
+		})
+
+		behavesLikeAnOperationStore(func() operations.OperationStore {
+			return open()
+		})
+
+		It("keeps operations when it is reopened", func() {
+			store := open()
+			Expect(store.Save(provision)).To(Succeed())
+			Expect(store.Save(bind)).To(Succeed())
+			Expect(store.Delete("bind")).To(Succeed())
+			Expect(store.Close()).To(Succeed())
+
+			Expect(open().List()).To(Equal([]operations.Operation{provision}))
+		})
+
+		It("appends changes to the journal and compacts it when it is reopened", func() {
+			store := open()
+			Expect(store.Save(provision)).To(Succeed())
+			Expect(store.Save(bind)).To(Succeed())
+			Expect(store.Delete("bind")).To(Succeed())
+			Expect(journal()).To(HaveLen(3))
+			Expect(store.Close()).To(Succeed())
+
+			open()
+			Expect(journal()).To(ConsistOf(
+				MatchJSON(`{"operation":{"id":"provision","type":"provision","instance_id":"an-instance","state":"in progress","started_at":"2024-05-01T12:00:00Z","updated_at":"2024-05-01T12:00:00Z"}}`),
+			))
+		})
+
+		It("compacts the journal when it has grown", func() {
+			store := open()
+			for range 1000 {
+				Expect(store.Save(provision)).To(Succeed())
+			}
+
+			Expect(journal()).To(HaveLen(1))
+			Expect(store.List()).To(Equal([]operations.Operation{provision}))
+		})
+
+		It("can be compacted on demand", func() {
+			store := open()
+			Expect(store.Save(provision)).To(Succeed())
+			Expect(store.Save(provision)).To(Succeed())
+
+			Expect(store.Compact()).To(Succeed())
+			Expect(journal()).To(HaveLen(1))
+
+			Expect(store.Save(bind)).To(Succeed())
+			Expect(journal()).To(HaveLen(2))
+		})
+
+		It("ignores an entry that was partially written", func() {
+			store := open()
+			Expect(store.Save(provision)).To(Succeed())
+			Expect(store.Close()).To(Succeed())
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = file.WriteString(`{"operation":{"id":"bind","ty`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			Expect(open().List()).To(Equal([]operations.Operation{provision}))
+		})
+
+		It("removes an entry that was partially written when the write fails", func() {
+			store := open()
+			Expect(store.Save(provision)).To(Succeed())
+
+			operations.SetJournalWriter(store, func(file *os.File, data []byte) (int, error) {
+				n, _ := file.Write(data[:len(data)/2])
+				return n, io.ErrShortWrite
+			})
+			Expect(store.Save(bind)).To(MatchError(ContainSubstring("short write")))
+
+			operations.SetJournalWriter(store, (*os.File).Write)
+			Expect(store.Delete("provision")).To(Succeed())
+			Expect(store.Save(bind)).To(Succeed())
+			Expect(store.Close()).To(Succeed())
+
+			Expect(journal()).To(HaveLen(3))
+			Expect(open().List()).To(Equal([]operations.Operation{bind}))
+		})
+
+		It("logs errors compacting the journal without failing the change", func() {
+			logs := gbytes.NewBuffer()
+			store, err := operations.NewFileStore(path, operations.WithCompactionLogger(slog.New(slog.NewJSONHandler(logs, nil))))
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() { store.Close() })
+
+			Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+			for range 1000 {
+				Expect(store.Save(provision)).To(Succeed())
+			}
+
+			Expect(logs).To(gbytes.Say(`"msg":"operations.compact-failed"`))
+			Expect(store.List()).To(Equal([]operations.Operation{provision}))
+		})
+
+		It("fails to open a corrupt journal", func() {
+			Expect(os.WriteFile(path, []byte("not json\n{}\n"), 0o600)).To(Succeed())
+
+			_, err := operations.NewFileStore(path)
+			Expect(err).To(MatchError(ContainSubstring("line 1")))
+		})
+	})
+})
@@ -11,6 +11,10 @@ type contextKey string
 const (
 	contextKeyService contextKey = "brokerapi_service"
 	contextKeyPlan    contextKey = "brokerapi_plan"
+
+	contextKeyPreviousPlan contextKey = "brokerapi_previous_plan"
+
+	contextKeyOriginatingIdentity contextKey = "brokerapi_originating_identity"
 )
 
 func AddServiceToContext(ctx context.Context, service *domain.Service) context.Context {
@@ -40,3 +44,35 @@ func RetrieveServicePlanFromContext(ctx context.Context) *domain.ServicePlan {
 	}
 	return nil
 }
+
+// AddPreviousServicePlanToContext records the plan that an instance had before an update
+func AddPreviousServicePlanToContext(ctx context.Context, plan *domain.ServicePlan) context.Context {
+	if plan != nil {
+		return context.WithValue(ctx, contextKeyPreviousPlan, plan)
+	}
+	return ctx
+}
+
+// RetrievePreviousServicePlanFromContext returns the plan that an instance had before an update
+func RetrievePreviousServicePlanFromContext(ctx context.Context) *domain.ServicePlan {
+	if value := ctx.Value(contextKeyPreviousPlan); value != nil {
+		return value.(*domain.ServicePlan)
+	}
+	return nil
+}
+
+func AddOriginatingIdentityToContext(ctx context.Context, identity *domain.OriginatingIdentity) context.Context {
+	if identity != nil {
+		return context.WithValue(ctx, contextKeyOriginatingIdentity, identity)
+	}
+	return ctx
+}
+
+// RetrieveOriginatingIdentityFromContext returns the parsed X-Broker-API-Originating-Identity header,
+// or nil if the header was absent or malformed
+func RetrieveOriginatingIdentityFromContext(ctx context.Context) *domain.OriginatingIdentity {
+	if value := ctx.Value(contextKeyOriginatingIdentity); value != nil {
+		return value.(*domain.OriginatingIdentity)
+	}
+	return nil
+}
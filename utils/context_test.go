@@ -73,4 +73,19 @@ var _ = Describe("Context", func() {
 			})
 		})
 	})
+
+	Describe("Previous Plan Context", func() {
+		It("returns the original context when the plan is nil", func() {
+			ctx = utils.AddPreviousServicePlanToContext(ctx, nil)
+			Expect(utils.RetrievePreviousServicePlanFromContext(ctx)).To(BeZero())
+			Expect(ctx.Value(contextValidatorKey).(string)).To(Equal(contextValidatorValue))
+		})
+
+		It("is independent of the current plan", func() {
+			ctx = utils.AddServicePlanToContext(ctx, &domain.ServicePlan{ID: "new-plan"})
+			ctx = utils.AddPreviousServicePlanToContext(ctx, &domain.ServicePlan{ID: "old-plan"})
+			Expect(utils.RetrieveServicePlanFromContext(ctx).ID).To(Equal("new-plan"))
+			Expect(utils.RetrievePreviousServicePlanFromContext(ctx).ID).To(Equal("old-plan"))
+		})
+	})
 })